@@ -33,6 +33,7 @@ const mapVoiceSentiment = (sentiment) => {
 const Home = () => {
     const [isRecording, setIsRecording] = useState(false);
     const [segments, setSegments] = useState([]);
+    const [interim, setInterim] = useState(null); // Süren konuşmanın geçici metni
     const [connectionStatus, setConnectionStatus] = useState('disconnected'); // disconnected, connecting, connected
 
     const socketRef = useRef(null);
@@ -45,7 +46,7 @@ const Home = () => {
 
     useEffect(() => {
         tableEndRef.current?.scrollIntoView({ behavior: "smooth" });
-    }, [segments]);
+    }, [segments, interim]);
 
     const startLiveAnalysis = async () => {
         try {
@@ -55,18 +56,43 @@ const Home = () => {
             socketRef.current = new WebSocket(WS_URL);
 
             socketRef.current.onopen = () => {
-                console.log('WS Connected, audio processing starting...');
-                setConnectionStatus('connected');
-                setIsRecording(true);
-                setSegments([]);
-                setupAudioProcessing(stream);
+                console.log('WS Connected, sending session start...');
+                // Session settings; audio starts after the server acknowledges
+                socketRef.current.send(JSON.stringify({
+                    v: 1,
+                    type: 'start',
+                    sample_rate: 16000,
+                    encoding: 'pcm_s16le',
+                    language: 'tr',
+                    vad_aggressiveness: 3,
+                    title: `Live Analysis ${new Date().toLocaleString()}`,
+                }));
             };
 
             socketRef.current.onmessage = (event) => {
                 try {
                     const data = JSON.parse(event.data);
-                    if (data.type === 'live_analysis') {
+                    if (data.type === 'session_started') {
+                        console.log('Session started:', data.session_id);
+                        setConnectionStatus('connected');
+                        setIsRecording(true);
+                        setSegments([]);
+                        setInterim(null);
+                        setupAudioProcessing(stream);
+                    } else if (data.type === 'interim_analysis') {
+                        setInterim({ seq: data.seq, ...data.payload });
+                    } else if (data.type === 'live_analysis') {
                         setSegments(prev => [...prev, data.payload]);
+                        // Aynı (veya önceki) segmentin geçici metni nihai sonuçla değişir
+                        setInterim(prev => (prev && prev.seq <= data.seq ? null : prev));
+                    } else if (data.type === 'session_complete') {
+                        console.log('Session complete, topic:', data.topic);
+                    } else if (data.type === 'service_error') {
+                        console.warn('Service error:', data.service, data.kind, data.message);
+                    } else if (data.type === 'overloaded') {
+                        console.warn('Server overloaded, results delayed:', data.scope, data.queue_depth);
+                    } else if (data.type === 'error') {
+                        console.error('Session error:', data.code, data.message);
                     }
                 } catch (e) {
                     console.error("JSON parse error:", e);
@@ -112,7 +138,7 @@ const Home = () => {
 
     const stopLiveAnalysis = () => {
         if (socketRef.current && socketRef.current.readyState === WebSocket.OPEN) {
-            socketRef.current.send("STOP");
+            socketRef.current.send(JSON.stringify({ v: 1, type: 'stop' }));
             setIsRecording(false);
             stopAudioProcessing();
         }
@@ -182,7 +208,7 @@ const Home = () => {
                         </tr>
                         </thead>
                         <tbody className="bg-white divide-y divide-gray-200">
-                        {segments.length === 0 ? (
+                        {segments.length === 0 && !interim ? (
                             <tr><td colSpan="5" className="px-6 py-12 text-center text-gray-400">Waiting for data...</td></tr>
                         ) : (
                             segments.map((seg, index) => (
@@ -230,6 +256,14 @@ const Home = () => {
                                 </tr>
                             ))
                         )}
+                        {interim && (
+                            <tr className="bg-gray-50">
+                                <td className="px-6 py-4 text-sm text-gray-400 font-mono">{formatTime(interim.start)}</td>
+                                <td className="px-6 py-4 text-xs text-gray-400">...</td>
+                                <td className="px-6 py-4 text-sm text-gray-500 italic">{interim.text}</td>
+                                <td colSpan="2"></td>
+                            </tr>
+                        )}
                         <div ref={tableEndRef} />
                         </tbody>
                     </table>
@@ -0,0 +1,33 @@
+package audio
+
+// SplitChannels: İç içe (interleaved) 16-bit PCM'i kanal başına mono PCM'e ayırır.
+// Sonda kalan yarım çerçeve rest olarak döner; çağıran bir sonraki parçanın başına ekler.
+func SplitChannels(pcm []byte, channels int) (out [][]byte, rest []byte) {
+	frame := 2 * channels
+	frames := len(pcm) / frame
+	out = make([][]byte, channels)
+	for c := range out {
+		out[c] = make([]byte, 2*frames)
+	}
+	for f := 0; f < frames; f++ {
+		for c := range out {
+			copy(out[c][2*f:2*f+2], pcm[f*frame+2*c:])
+		}
+	}
+	return out, pcm[frames*frame:]
+}
+
+// InterleaveChannels: Eşit uzunluktaki mono kanalları iç içe 16-bit PCM'e birleştirir (SplitChannels'ın tersi)
+func InterleaveChannels(chs [][]byte) []byte {
+	if len(chs) == 0 {
+		return nil
+	}
+	frames := len(chs[0]) / 2
+	out := make([]byte, 2*frames*len(chs))
+	for f := 0; f < frames; f++ {
+		for c, ch := range chs {
+			copy(out[2*(f*len(chs)+c):], ch[2*f:2*f+2])
+		}
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+// ErrUnknownFormat: Dosyanın biçimi hiçbir kayıtlı çözücü tarafından tanınmadı
+var ErrUnknownFormat = errors.New("tanınmayan ses biçimi")
+
+// ErrTooLong: Çözülen ses izin verilen süreyi aşıyor
+var ErrTooLong = errors.New("ses çok uzun")
+
+// ErrChannelCount: Dosyanın kanal sayısı istenenle uyuşmuyor
+var ErrChannelCount = errors.New("kanal sayısı uyuşmuyor")
+
+// sniffSize: Biçim tanıma için dosyanın başından okunan byte sayısı
+const sniffSize = 512
+
+// DecodeOptions: Çözülen sesin hedef biçimi ve sınırları
+type DecodeOptions struct {
+	SampleRate int   // Çıkış: bu hızda 16-bit little-endian PCM
+	MaxBytes   int64 // Çıkışın üst sınırı; aşılırsa ErrTooLong (0: sınırsız)
+	// Channels 0: mono'ya indirilir. 0'dan büyükse kanallar iç içe korunur; WAV bu kadar kanal içermeli
+	// (yoksa ErrChannelCount), ffmpeg biçimlerinde kanallar bu sayıya eşlenir.
+	Channels int
+}
+
+// outChannels: Çıkıştaki kanal sayısı
+func (o DecodeOptions) outChannels() int {
+	return max(o.Channels, 1)
+}
+
+// Decoder: Yüklenen ses dosyalarının bir ailesini çözer
+type Decoder interface {
+	// Sniff: Dosyanın ilk byte'larından biçimi tanır (head, sniffSize'dan kısa olabilir)
+	Sniff(head []byte) (format string, ok bool)
+	// Decode: Sesi opts'taki biçime çözer; ctx iptal edilir veya süresi dolarsa durur
+	Decode(ctx context.Context, r io.Reader, opts DecodeOptions) ([]byte, error)
+}
+
+// Registry: Dosyanın biçimini tanıyıp uygun çözücüye verir. Çözücüler kayıt sırasıyla denenir.
+type Registry struct {
+	decoders []Decoder
+}
+
+// NewRegistry: Verilen çözücülerle (öncelik sırasıyla) bir kayıt
+func NewRegistry(decoders ...Decoder) *Registry {
+	return &Registry{decoders: decoders}
+}
+
+// NewDefaultRegistry: WAV Go içinde çözülür; diğer yaygın biçimler ffmpeg'e verilir
+func NewDefaultRegistry(ffmpegPath string) *Registry {
+	return NewRegistry(WAVDecoder{}, FFmpegDecoder{Path: ffmpegPath})
+}
+
+// Register: Çözücüyü en düşük öncelikle ekler
+func (g *Registry) Register(d Decoder) {
+	g.decoders = append(g.decoders, d)
+}
+
+// Decode: Dosyanın biçimini tanır ve çözer. format: tanınan biçim (hata durumunda da dolu olabilir)
+func (g *Registry) Decode(ctx context.Context, r io.Reader, opts DecodeOptions) (pcm []byte, format string, err error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	for _, d := range g.decoders {
+		if format, ok := d.Sniff(head); ok {
+			pcm, err := d.Decode(ctx, br, opts)
+			return pcm, format, err
+		}
+	}
+	return nil, "", ErrUnknownFormat
+}
+
+// --- WAV ---
+
+// WAVDecoder: PCM16/PCM24/float32 WAV'ı ffmpeg'siz çözer; gerekirse mono'ya indirip yeniden örnekler
+type WAVDecoder struct{}
+
+func (WAVDecoder) Sniff(head []byte) (string, bool) {
+	ok := len(head) >= 12 && (string(head[:4]) == "RIFF" || string(head[:4]) == "RF64") && string(head[8:12]) == "WAVE"
+	return "wav", ok
+}
+
+func (WAVDecoder) Decode(ctx context.Context, r io.Reader, opts DecodeOptions) ([]byte, error) {
+	wr, err := NewWAVReader(r)
+	if err != nil {
+		return nil, err
+	}
+	f := wr.Format()
+	if opts.Channels > 0 && f.Channels != opts.Channels {
+		return nil, fmt.Errorf("%w: dosyada %d kanal var, %d bekleniyor", ErrChannelCount, f.Channels, opts.Channels)
+	}
+	if opts.MaxBytes > 0 && wr.Frames() > 0 {
+		// Çıkış boyutu başlıktan bellidir; sınırı aşan dosya okunmadan reddedilir
+		if out := wr.Frames() * int64(opts.SampleRate) / int64(f.SampleRate) * 2 * int64(opts.outChannels()); out > opts.MaxBytes {
+			return nil, fmt.Errorf("%w: %s", ErrTooLong, wr.Duration())
+		}
+	}
+
+	// Kanallar korunacaksa her kanal kendi örnekleyicisinden geçer; yoksa örnekleyici mono'ya indirir
+	var res []*Resampler
+	switch {
+	case opts.Channels > 0 && f.SampleRate != opts.SampleRate:
+		for range f.Channels {
+			res = append(res, NewResampler(f.SampleRate, opts.SampleRate, 1))
+		}
+	case opts.Channels == 0 && (f.SampleRate != opts.SampleRate || f.Channels > 1):
+		res = []*Resampler{NewResampler(f.SampleRate, opts.SampleRate, f.Channels)}
+	}
+	process := func(pcm []byte, flush bool) []byte {
+		if len(res) == 1 {
+			if flush {
+				return res[0].Flush()
+			}
+			return res[0].Process(pcm)
+		}
+		chs, _ := SplitChannels(pcm, len(res))
+		for c, r := range res {
+			if flush {
+				chs[c] = r.Flush()
+			} else {
+				chs[c] = r.Process(chs[c])
+			}
+		}
+		return InterleaveChannels(chs)
+	}
+
+	var out []byte
+	buf := make([]byte, 2*f.Channels*4096)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := wr.ReadPCM16(buf)
+		pcm := buf[:n]
+		if res != nil {
+			pcm = process(pcm, false)
+		}
+		out = append(out, pcm...)
+		if opts.MaxBytes > 0 && int64(len(out)) > opts.MaxBytes {
+			return nil, ErrTooLong
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+		}
+	}
+	if res != nil {
+		out = append(out, process(nil, true)...)
+	}
+	return out, nil
+}
+
+// --- ffmpeg ---
+
+// ffmpegSignatures: ffmpeg'e verilen biçimlerin imzaları. Tanınmayan dosyalar ffmpeg'e hiç
+// verilmez; böylece keyfi girdiler ffmpeg'in bütün demuxer'larına açılmaz.
+var ffmpegSignatures = []struct {
+	format string
+	offset int
+	magic  string
+}{
+	{"webm", 0, "\x1a\x45\xdf\xa3"}, // EBML (WebM/Matroska)
+	{"ogg", 0, "OggS"},
+	{"flac", 0, "fLaC"},
+	{"mp3", 0, "ID3"},
+	{"mp4", 4, "ftyp"}, // MP4/M4A (ISO BMFF)
+}
+
+// FFmpegDecoder: Sıkıştırılmış biçimleri ffmpeg alt süreciyle çözer
+type FFmpegDecoder struct {
+	Path string
+}
+
+func (FFmpegDecoder) Sniff(head []byte) (string, bool) {
+	for _, s := range ffmpegSignatures {
+		if len(head) >= s.offset+len(s.magic) && string(head[s.offset:s.offset+len(s.magic)]) == s.magic {
+			return s.format, true
+		}
+	}
+	// ID3 etiketi olmayan MP3: MPEG ses çerçevesi senkronu
+	if len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0 {
+		return "mp3", true
+	}
+	return "", false
+}
+
+// Decode: ctx'in süresi dolar veya çıkış sınırı aşılırsa süreç öldürülür. Hata mesajı
+// ffmpeg'in stderr çıktısını içerir.
+func (d FFmpegDecoder) Decode(ctx context.Context, r io.Reader, opts DecodeOptions) ([]byte, error) {
+	if err := FFmpegAvailable(d.Path); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, d.Path,
+		"-hide_banner", "-loglevel", "error", "-nostdin",
+		"-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le", "-ac", strconv.Itoa(opts.outChannels()), "-ar", strconv.Itoa(opts.SampleRate),
+		"pipe:1",
+	)
+	cmd.WaitDelay = ffmpegWaitDelay
+	out := &limitedBuffer{max: opts.MaxBytes, cancel: cancel}
+	stderr := &tailBuffer{}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, out, stderr
+
+	err := cmd.Run()
+	switch {
+	case out.exceeded:
+		return nil, ErrTooLong
+	case ctx.Err() != nil:
+		// Süre doldu veya istek iptal edildi; süreç öldürüldüğü için stderr'i anlamsız
+		return nil, fmt.Errorf("ffmpeg: %w", ctx.Err())
+	case err != nil:
+		return nil, ffmpegError(err, stderr)
+	}
+	return out.buf.Bytes(), nil
+}
+
+// limitedBuffer: max'ı aşan ilk yazmada hata döner ve süreci iptal eder. bytes.Buffer gömülmez:
+// io.Copy onun ReadFrom'unu kullanıp sınırı atlardı.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int64
+	cancel   context.CancelFunc
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		b.exceeded = true
+		b.cancel()
+		return 0, ErrTooLong
+	}
+	return b.buf.Write(p)
+}
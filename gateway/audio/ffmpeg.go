@@ -0,0 +1,71 @@
+// Package audio: Gateway'in ses biçimi dönüşümleri (sıkıştırılmış akışların PCM'e çözülmesi vb.)
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFFmpegMissing: Yapılandırılan ffmpeg çalıştırılabilir dosyası bulunamadı
+var ErrFFmpegMissing = errors.New("ffmpeg bulunamadı")
+
+// ffmpegLookups: Yol başına FFmpegAvailable sonucu (error veya nil)
+var ffmpegLookups sync.Map
+
+// FFmpegAvailable: ffmpeg'in çalıştırılabilir olduğunu doğrular. PATH her akış başlangıcında
+// taranmasın diye sonuç yol başına ilk sorguda (main.go'da açılışta) çözülüp saklanır; sunucu
+// çalışırken kurulan veya kaldırılan ffmpeg yeniden başlatınca görülür.
+func FFmpegAvailable(path string) error {
+	if v, ok := ffmpegLookups.Load(path); ok {
+		err, _ := v.(error)
+		return err
+	}
+	var err error
+	if _, lookErr := exec.LookPath(path); lookErr != nil {
+		err = fmt.Errorf("%w (%s): %v", ErrFFmpegMissing, path, lookErr)
+	}
+	ffmpegLookups.Store(path, err)
+	return err
+}
+
+// ffmpegWaitDelay: ctx dolup ffmpeg öldürüldükten sonra çıkış borularının kapanması için beklenen
+// en fazla süre. ffmpeg'in (veya sarmalayan betiğin) başlattığı alt süreçler boruları açık tutarsa
+// Wait bu süre sonunda boruları kapatıp döner; yoksa o süreçler bitene kadar takılırdı.
+const ffmpegWaitDelay = time.Second
+
+// stderrLimit: Hata mesajına eklenecek ffmpeg çıktısının üst sınırı (son kısım tutulur)
+const stderrLimit = 4 << 10
+
+// tailBuffer: Sadece son stderrLimit byte'ı tutan, eşzamanlı yazmaya dayanıklı tampon
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - stderrLimit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.buf))
+}
+
+// ffmpegError: Çıkış hatasına ffmpeg'in kendi açıklamasını ekler
+func ffmpegError(err error, stderr *tailBuffer) error {
+	if msg := stderr.String(); msg != "" {
+		return fmt.Errorf("ffmpeg: %w: %s", err, msg)
+	}
+	return fmt.Errorf("ffmpeg: %w", err)
+}
@@ -0,0 +1,132 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// orphanFFmpeg: Boruları miras alan bir alt süreç başlatıp bekleyen sahte ffmpeg. Kabuk öldürülse
+// de alt süreç stdout/stderr'i açık tutar. started, alt süreç başladığında oluşturulur.
+func orphanFFmpeg(t *testing.T) (path, started string) {
+	t.Helper()
+	dir := t.TempDir()
+	path, started = filepath.Join(dir, "ffmpeg"), filepath.Join(dir, "started")
+	script := "#!/bin/sh\nsleep 10 &\ntouch " + started + "\nwait\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, started
+}
+
+func TestFFmpegDecodeReturnsAfterTimeoutDespiteOrphans(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	ffmpeg, _ := orphanFFmpeg(t)
+	_, err := FFmpegDecoder{Path: ffmpeg}.Decode(ctx, bytes.NewReader(nil), DecodeOptions{SampleRate: 16000})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*ffmpegWaitDelay {
+		t.Errorf("Decode returned after %v, want within ~%v of the deadline", elapsed, ffmpegWaitDelay)
+	}
+}
+
+func TestStreamDecoderFinishesAfterCancelDespiteOrphans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ffmpeg, started := orphanFFmpeg(t)
+	d, err := NewStreamDecoder(ctx, ffmpeg, FormatWebM, 16000, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, err := os.Stat(started); err != nil; _, err = os.Stat(started) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	deadline := time.After(3 * ffmpegWaitDelay)
+	for {
+		select {
+		case <-d.Ready():
+			if _, done, _ := d.Drain(); done {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("stream decoder not done %v after cancel", 3*ffmpegWaitDelay)
+		}
+	}
+}
+
+// Girişi okumayan ffmpeg okuma döngüsünü kilitlemez: Write süre dolunca döner ve akış hatayla biter
+func TestStreamDecoderWriteTimesOutWhenStalled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	const timeout = 100 * time.Millisecond
+	d, err := NewStreamDecoder(context.Background(), path, FormatWebM, 16000, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	start := time.Now()
+	// Boru tamponundan (64 KB) büyük olmalı ki yazma beklesin
+	if _, err := d.Write(make([]byte, 1<<20)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Errorf("Write returned after %v, want ~%v", elapsed, timeout)
+	}
+
+	deadline := time.After(3 * ffmpegWaitDelay)
+	for {
+		select {
+		case <-d.Ready():
+			_, done, err := d.Drain()
+			if !done {
+				continue
+			}
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("stream ended with %v, want the write timeout", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("stalled decoder not stopped after the write timeout")
+		}
+	}
+}
+
+func TestFFmpegAvailableCachesLookup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ffmpeg")
+	if err := FFmpegAvailable(path); !errors.Is(err, ErrFFmpegMissing) {
+		t.Fatalf("missing ffmpeg: err = %v, want ErrFFmpegMissing", err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Açılıştaki sonuç geçerli kalır; dosya sistemi tekrar taranmaz
+	if err := FFmpegAvailable(path); !errors.Is(err, ErrFFmpegMissing) {
+		t.Errorf("after install: err = %v, want the cached ErrFFmpegMissing", err)
+	}
+
+	other := filepath.Join(dir, "ffmpeg-installed")
+	if err := os.WriteFile(other, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := FFmpegAvailable(other); err != nil {
+		t.Errorf("installed ffmpeg: err = %v", err)
+	}
+	os.Remove(other)
+	if err := FFmpegAvailable(other); err != nil {
+		t.Errorf("after removal: err = %v, want the cached nil", err)
+	}
+}
@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Resampler filtre parametreleri
+const (
+	resampleZeroCrossings = 16   // Filtrenin her iki yanındaki sinc sıfır geçişi (kalite/maliyet dengesi)
+	resampleRolloff       = 0.94 // Kesim frekansı, Nyquist'in bu oranında (geçiş bandı için pay)
+	resampleKaiserBeta    = 8.6  // ~80 dB durdurma bandı bastırması
+)
+
+// Resampler: Akış halinde gelen, kanalları iç içe (interleaved) 16-bit little-endian PCM'i
+// mono'ya indirger ve hedef örnekleme hızına dönüştürür.
+//
+// Dönüşüm Kaiser pencereli sinc filtreli çok fazlı (polyphase) bir bankla yapılır. Hedef hız
+// düşükse kesim frekansı hedefin Nyquist'ine çekilir; böylece 48 kHz'deki 8 kHz üstü bileşenler
+// 16 kHz'e katlanmaz (aliasing). Parçalar herhangi bir yerden bölünebilir: yarım kalan çerçeve
+// ve filtre geçmişi bir sonraki çağrıya taşınır, çıktı tek seferde işlenmiş sesle aynıdır.
+// Eşzamanlı kullanıma uygun değildir.
+type Resampler struct {
+	channels int
+	up, down int         // Çıkış/giriş hız oranı (sadeleştirilmiş)
+	half     int         // Filtrenin yarı genişliği (giriş örneği)
+	bank     [][]float32 // up faz × 2*half katsayı
+
+	pending []byte    // Bir sonraki çağrıya kalan yarım çerçeve
+	in      []float32 // Mono giriş; in[0], base indeksli giriş örneği
+	base    int64
+	next    int64 // Sıradaki çıkış örneğinin indeksi
+}
+
+// NewResampler: from Hz, channels kanallı girişi to Hz mono çıkışa dönüştüren Resampler
+func NewResampler(from, to, channels int) *Resampler {
+	g := gcd(from, to)
+	r := &Resampler{channels: channels, up: to / g, down: from / g}
+	if r.up == r.down {
+		return r // Sadece kanal indirgeme
+	}
+
+	// Kesim, giriş Nyquist'ine göre: yukarı örneklemede girişin, aşağı örneklemede çıkışın bandı
+	fc := resampleRolloff * math.Min(1, float64(r.up)/float64(r.down))
+	r.half = int(math.Ceil(resampleZeroCrossings / fc))
+	r.bank = make([][]float32, r.up)
+	for p := range r.bank {
+		frac := float64(p) / float64(r.up)
+		taps := make([]float32, 2*r.half)
+		var sum float64
+		for j := range taps {
+			d := float64(r.half-1-j) + frac // Çıkış anının j. katsayının giriş örneğine uzaklığı
+			v := fc * sinc(fc*d) * kaiser(d/float64(r.half))
+			taps[j] = float32(v)
+			sum += v
+		}
+		// Her faz birim DC kazancına normalize edilir (faza bağlı genlik dalgalanması olmasın)
+		for j := range taps {
+			taps[j] = float32(float64(taps[j]) / sum)
+		}
+		r.bank[p] = taps
+	}
+	return r
+}
+
+// Process: Gelen parçayı dönüştürür; filtrenin ihtiyaç duyduğu gelecek örnekler henüz gelmediyse
+// son birkaç milisaniye bir sonraki çağrıda (veya Flush'ta) döner.
+func (r *Resampler) Process(pcm []byte) []byte {
+	data := pcm
+	if len(r.pending) > 0 {
+		data = append(r.pending, pcm...)
+	}
+	frame := 2 * r.channels
+	frames := len(data) / frame
+	for f := 0; f < frames; f++ {
+		var sum int32
+		for c := 0; c < r.channels; c++ {
+			sum += int32(int16(binary.LittleEndian.Uint16(data[f*frame+2*c:])))
+		}
+		r.in = append(r.in, float32(sum)/float32(r.channels))
+	}
+	r.pending = append([]byte(nil), data[frames*frame:]...)
+
+	if r.up == r.down {
+		out := make([]byte, 0, 2*len(r.in))
+		for _, v := range r.in {
+			out = appendSample(out, v)
+		}
+		r.in = r.in[:0]
+		return out
+	}
+	return r.produce(math.MaxInt64)
+}
+
+// Flush: Akış bitti; filtrenin beklettiği son örnekleri döner
+func (r *Resampler) Flush() []byte {
+	if r.up == r.down {
+		return nil
+	}
+	total := r.base + int64(len(r.in))
+	r.in = append(r.in, make([]float32, r.half)...)
+	return r.produce(total)
+}
+
+// produce: Filtre penceresi tamamen elde olan çıkış örneklerini üretir; inputEnd'e ve sonrasına
+// denk gelen çıkışlar üretilmez (Flush'taki sıfır dolgu için)
+func (r *Resampler) produce(inputEnd int64) []byte {
+	var out []byte
+	available := r.base + int64(len(r.in))
+	for {
+		pos := r.next * int64(r.down)
+		i, p := pos/int64(r.up), pos%int64(r.up)
+		if i+int64(r.half) >= available || i >= inputEnd {
+			break
+		}
+		first := i - int64(r.half) + 1 - r.base
+		var acc float32
+		for j, c := range r.bank[p] {
+			// Akışın başından önceki örnekler sıfır kabul edilir
+			if k := first + int64(j); k >= 0 {
+				acc += c * r.in[k]
+			}
+		}
+		out = appendSample(out, acc)
+		r.next++
+	}
+
+	// Artık hiçbir çıkışın ihtiyaç duymadığı geçmiş atılır
+	keep := (r.next*int64(r.down))/int64(r.up) - int64(r.half) + 1 - r.base
+	if keep > 0 {
+		keep = min(keep, int64(len(r.in)))
+		r.in = append(r.in[:0], r.in[keep:]...)
+		r.base += keep
+	}
+	return out
+}
+
+func appendSample(out []byte, v float32) []byte {
+	s := math.Round(float64(v))
+	s = math.Max(math.MinInt16, math.Min(math.MaxInt16, s))
+	return binary.LittleEndian.AppendUint16(out, uint16(int16(s)))
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser: |x| <= 1 için Kaiser penceresi
+func kaiser(x float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	return besselI0(resampleKaiserBeta*math.Sqrt(1-x*x)) / besselI0(resampleKaiserBeta)
+}
+
+// besselI0: Birinci tür sıfırıncı derece değiştirilmiş Bessel fonksiyonu (seri açılımı)
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+const resampleTarget = 16000
+
+// tonePCM: channels kanallı, her kanalda aynı sinüs (genlik tam ölçeğin yarısı)
+func tonePCM(rate, channels int, freq, seconds float64) []byte {
+	n := int(seconds * float64(rate))
+	buf := make([]byte, 0, 2*n*channels)
+	for i := 0; i < n; i++ {
+		v := int16(16384 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+		for c := 0; c < channels; c++ {
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(v))
+		}
+	}
+	return buf
+}
+
+func samples(pcm []byte) []float64 {
+	out := make([]float64, len(pcm)/2)
+	for i := range out {
+		out[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+	}
+	return out
+}
+
+// toneSNR: Çıkışın ideal 16 kHz sinüse göre sinyal/gürültü oranı (dB). Başta ve sonda filtrenin
+// sıfır dolguyla beslendiği kısım dışarıda bırakılır.
+func toneSNR(out []byte, freq float64) float64 {
+	s := samples(out)
+	var sig, noise float64
+	for i := 200; i < len(s)-200; i++ {
+		ideal := 16384 * math.Sin(2*math.Pi*freq*float64(i)/resampleTarget)
+		sig += ideal * ideal
+		noise += (s[i] - ideal) * (s[i] - ideal)
+	}
+	return 10 * math.Log10(sig/noise)
+}
+
+func resampleAll(r *Resampler, pcm []byte, chunk int) []byte {
+	var out []byte
+	for i := 0; i < len(pcm); i += chunk {
+		out = append(out, r.Process(pcm[i:min(i+chunk, len(pcm))])...)
+	}
+	return append(out, r.Flush()...)
+}
+
+func TestResamplerQuality(t *testing.T) {
+	for _, rate := range []int{8000, 22050, 32000, 44100, 48000} {
+		for _, channels := range []int{1, 2} {
+			t.Run(fmt.Sprintf("%d/%dch", rate, channels), func(t *testing.T) {
+				out := resampleAll(NewResampler(rate, resampleTarget, channels), tonePCM(rate, channels, 1000, 1), 4096)
+
+				if want := resampleTarget; math.Abs(float64(len(out)/2-want)) > 1 {
+					t.Errorf("got %d samples, want %d", len(out)/2, want)
+				}
+				if snr := toneSNR(out, 1000); snr < 60 {
+					t.Errorf("SNR = %.1f dB, want >= 60", snr)
+				}
+			})
+		}
+	}
+}
+
+// Hedefin Nyquist'i (8 kHz) üstündeki bileşen çıkışa katlanmamalı
+func TestResamplerRejectsAliases(t *testing.T) {
+	for _, rate := range []int{22050, 44100, 48000} {
+		out := resampleAll(NewResampler(rate, resampleTarget, 1), tonePCM(rate, 1, 9500, 1), 4096)
+		var sum float64
+		s := samples(out)
+		for _, v := range s[200 : len(s)-200] {
+			sum += v * v
+		}
+		rms := math.Sqrt(sum / float64(len(s)-400))
+		if db := 20 * math.Log10(rms/(16384/math.Sqrt2)); db > -60 {
+			t.Errorf("%d Hz: 9.5 kHz tone leaked at %.1f dB, want <= -60", rate, db)
+		}
+	}
+}
+
+// Parça sınırları (yarım çerçeveler dahil) çıkışı değiştirmemeli
+func TestResamplerChunkingInvariant(t *testing.T) {
+	in := tonePCM(44100, 2, 440, 0.5)
+	whole := resampleAll(NewResampler(44100, resampleTarget, 2), in, len(in))
+	for _, chunk := range []int{1, 3, 641, 4410} {
+		if got := resampleAll(NewResampler(44100, resampleTarget, 2), in, chunk); !bytes.Equal(got, whole) {
+			t.Errorf("chunk %d: output differs from single-pass output", chunk)
+		}
+	}
+}
+
+// BenchmarkResampler: Gerçek zamanlı bir saniyelik ses başına maliyet; kaliteyi de snr_dB olarak raporlar.
+//
+//	go test ./audio -bench Resampler -run '^$'
+func BenchmarkResampler(b *testing.B) {
+	for _, tc := range []struct {
+		rate, channels int
+	}{{8000, 1}, {22050, 1}, {44100, 2}, {48000, 1}, {48000, 2}} {
+		b.Run(fmt.Sprintf("%d/%dch", tc.rate, tc.channels), func(b *testing.B) {
+			in := tonePCM(tc.rate, tc.channels, 1000, 1)
+			chunk := 2 * tc.channels * tc.rate / 50 // 20 ms'lik WebSocket çerçeveleri
+			b.SetBytes(int64(len(in)))
+			var out []byte
+			for b.Loop() {
+				out = resampleAll(NewResampler(tc.rate, resampleTarget, tc.channels), in, chunk)
+			}
+			b.ReportMetric(toneSNR(out, 1000), "snr_dB")
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Akış biçimleri (ffmpeg demuxer adları)
+const (
+	FormatWebM = "webm" // MediaRecorder'ın varsayılanı: Opus-in-WebM
+	FormatOgg  = "ogg"  // Opus-in-Ogg
+)
+
+// StreamDecoder: Parça parça gelen kodlanmış sesi 16-bit little-endian mono PCM'e çözer.
+//
+// Write kodlanmış veriyi çözücüye verir. Çözülen PCM biriktirilir ve Ready sinyal verince
+// Drain ile alınır; çözücü okuyanı beklemediği için Write hiçbir zaman çıktı yüzünden tıkanmaz.
+// Close girişi kapatır; kalan ses çözülür ve Drain done=true döndüğünde akış bitmiştir.
+// Çözücü girişi okumayı bırakırsa Write beklemez: süre dolunca çözücü durdurulur ve hata Drain ile döner.
+type StreamDecoder interface {
+	io.Writer
+	Ready() <-chan struct{}
+	Drain() (pcm []byte, done bool, err error)
+	Close() error
+}
+
+// ffmpegStream: ffmpeg alt süreciyle çalışan StreamDecoder
+type ffmpegStream struct {
+	cmd          *exec.Cmd
+	stdin        *os.File // Yazma süresi sınırlanabilsin diye exec'in StdinPipe'ı yerine doğrudan boru
+	writeTimeout time.Duration
+	stderr       *tailBuffer
+	ready        chan struct{}
+
+	mu   sync.Mutex
+	pcm  []byte
+	done bool
+	err  error
+}
+
+// NewStreamDecoder: format'taki akışı sampleRate Hz mono PCM'e çözen ffmpeg sürecini başlatır.
+// ctx iptal edilirse veya ffmpeg bir Write'ı writeTimeout içinde okumazsa süreç öldürülür.
+func NewStreamDecoder(ctx context.Context, ffmpegPath, format string, sampleRate int, writeTimeout time.Duration) (StreamDecoder, error) {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-f", format, "-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le", "-ac", "1", "-ar", strconv.Itoa(sampleRate),
+		"pipe:1",
+	)
+	cmd.WaitDelay = ffmpegWaitDelay
+	d := &ffmpegStream{cmd: cmd, writeTimeout: writeTimeout, stderr: &tailBuffer{}, ready: make(chan struct{}, 1)}
+	cmd.Stderr = d.stderr
+
+	stdinR, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = stdinR
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdinR.Close()
+		stdin.Close()
+		return nil, err
+	}
+	err = cmd.Start()
+	stdinR.Close() // Okuma ucu artık sadece ffmpeg'de
+	if err != nil {
+		stdin.Close()
+		if _, lookErr := exec.LookPath(ffmpegPath); lookErr != nil {
+			return nil, fmt.Errorf("%w (%s)", ErrFFmpegMissing, ffmpegPath)
+		}
+		return nil, ffmpegError(err, d.stderr)
+	}
+	d.stdin = stdin
+	go d.pump(stdout)
+	return d, nil
+}
+
+// pump: ffmpeg çıktısını okuyanı beklemeden biriktirir
+func (d *ffmpegStream) pump(stdout io.Reader) {
+	buf := make([]byte, 8<<10)
+	for {
+		n, err := stdout.Read(buf)
+		if n > 0 {
+			d.mu.Lock()
+			d.pcm = append(d.pcm, buf[:n]...)
+			d.mu.Unlock()
+			d.signal()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	err := d.cmd.Wait()
+	d.mu.Lock()
+	d.done = true
+	if err != nil && d.err == nil {
+		d.err = ffmpegError(err, d.stderr)
+	}
+	d.mu.Unlock()
+	d.signal()
+}
+
+func (d *ffmpegStream) signal() {
+	select {
+	case d.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (d *ffmpegStream) Write(p []byte) (int, error) {
+	d.stdin.SetWriteDeadline(time.Now().Add(d.writeTimeout))
+	n, err := d.stdin.Write(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		// ffmpeg takıldı: süreç öldürülür, pump bitişi bu hatayla bildirir
+		err = fmt.Errorf("ffmpeg girişi %v içinde okumadı: %w", d.writeTimeout, err)
+		d.mu.Lock()
+		if d.err == nil {
+			d.err = err
+		}
+		d.mu.Unlock()
+		d.cmd.Process.Kill()
+		return n, err
+	}
+	if err != nil {
+		return n, fmt.Errorf("ffmpeg girişi yazılamadı: %w", err)
+	}
+	return n, nil
+}
+
+func (d *ffmpegStream) Ready() <-chan struct{} {
+	return d.ready
+}
+
+func (d *ffmpegStream) Drain() ([]byte, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pcm := d.pcm
+	d.pcm = nil
+	return pcm, d.done, d.err
+}
+
+func (d *ffmpegStream) Close() error {
+	return d.stdin.Close()
+}
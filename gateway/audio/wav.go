@@ -0,0 +1,379 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrInvalidWAV: Girdi okunabilir bir RIFF/WAVE dosyası değil
+var ErrInvalidWAV = errors.New("geçersiz WAV")
+
+// ErrUnsupportedWAV: Dosya geçerli ama örnek biçimi desteklenmiyor (örn. 8-bit, ADPCM, RF64)
+var ErrUnsupportedWAV = errors.New("desteklenmeyen WAV biçimi")
+
+// SampleFormat: WAV örnek biçimi
+type SampleFormat int
+
+const (
+	PCM16   SampleFormat = iota + 1 // 16-bit işaretli tamsayı
+	PCM24                           // 24-bit işaretli tamsayı
+	Float32                         // IEEE 754 32-bit kayan nokta, [-1, 1]
+)
+
+// BitsPerSample: Bir örneğin bit sayısı
+func (f SampleFormat) BitsPerSample() int {
+	switch f {
+	case PCM16:
+		return 16
+	case PCM24:
+		return 24
+	case Float32:
+		return 32
+	}
+	return 0
+}
+
+func (f SampleFormat) String() string {
+	switch f {
+	case PCM16:
+		return "pcm_s16le"
+	case PCM24:
+		return "pcm_s24le"
+	case Float32:
+		return "pcm_f32le"
+	}
+	return fmt.Sprintf("SampleFormat(%d)", int(f))
+}
+
+// WAV format etiketleri (WAVE_FORMAT_*)
+const (
+	wavTagPCM        = 0x0001
+	wavTagFloat      = 0x0003
+	wavTagExtensible = 0xFFFE
+)
+
+// wavUnknownSize: Boyutu baştan bilinmeyen akışlarda (ffmpeg pipe çıktısı gibi) RIFF/data boyutu
+const wavUnknownSize = math.MaxUint32
+
+// maxWAVChannels: Okunan dosyalarda kabul edilen en fazla kanal
+const maxWAVChannels = 64
+
+// Format: Ses biçimi metadatası
+type Format struct {
+	SampleFormat SampleFormat
+	SampleRate   int
+	Channels     int
+}
+
+// FrameSize: Bütün kanallarıyla bir örnek anının byte uzunluğu (WAV block align)
+func (f Format) FrameSize() int {
+	return f.Channels * f.SampleFormat.BitsPerSample() / 8
+}
+
+// BytesPerSecond: Saniyedeki ses verisi
+func (f Format) BytesPerSecond() int {
+	return f.SampleRate * f.FrameSize()
+}
+
+// Duration: frames örnek anının süresi
+func (f Format) Duration(frames int64) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(f.SampleRate)
+}
+
+func (f Format) validate() error {
+	if f.SampleFormat.BitsPerSample() == 0 {
+		return fmt.Errorf("%w: örnek biçimi %v", ErrUnsupportedWAV, f.SampleFormat)
+	}
+	if f.SampleRate <= 0 || f.Channels <= 0 || f.Channels > maxWAVChannels {
+		return fmt.Errorf("%w: %d Hz, %d kanal", ErrInvalidWAV, f.SampleRate, f.Channels)
+	}
+	return nil
+}
+
+// --- Okuma ---
+
+// WAVReader: WAV dosyasını akış halinde okur; başlık okunduktan sonra ses verisi parça parça
+// alınır, dosyanın tamamı belleğe yüklenmez. Bilinmeyen chunk'lar (LIST, fact vb.) atlanır.
+type WAVReader struct {
+	format Format
+	frames int64 // -1: data boyutu bilinmiyor (akış), sona kadar okunur
+	data   io.Reader
+	raw    []byte // ReadPCM16 için ara tampon
+}
+
+// NewWAVReader: RIFF başlığını ve "data" chunk'ına kadarki chunk'ları okur
+func NewWAVReader(r io.Reader) (*WAVReader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("%w: RIFF başlığı okunamadı: %v", ErrInvalidWAV, err)
+	}
+	if string(riff[:4]) == "RF64" {
+		return nil, fmt.Errorf("%w: RF64", ErrUnsupportedWAV)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, fmt.Errorf("%w: RIFF/WAVE imzası yok", ErrInvalidWAV)
+	}
+
+	var format *Format
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, fmt.Errorf("%w: data chunk'ı bulunamadı: %v", ErrInvalidWAV, err)
+		}
+		id, size := string(hdr[:4]), binary.LittleEndian.Uint32(hdr[4:])
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return nil, fmt.Errorf("%w: fmt chunk'ı %d byte", ErrInvalidWAV, size)
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, fmt.Errorf("%w: fmt chunk'ı okunamadı: %v", ErrInvalidWAV, err)
+			}
+			f, err := parseFmtChunk(buf[:size])
+			if err != nil {
+				return nil, err
+			}
+			format = &f
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("%w: data chunk'ı fmt'den önce", ErrInvalidWAV)
+			}
+			w := &WAVReader{format: *format, frames: -1, data: r}
+			if size != wavUnknownSize && size != 0 {
+				w.frames = int64(size) / int64(format.FrameSize())
+				w.data = io.LimitReader(r, w.frames*int64(format.FrameSize()))
+			}
+			return w, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size)+int64(size%2)); err != nil {
+				return nil, fmt.Errorf("%w: %q chunk'ı atlanamadı: %v", ErrInvalidWAV, id, err)
+			}
+		}
+	}
+}
+
+// parseFmtChunk: WAVEFORMATEX / WAVEFORMATEXTENSIBLE
+func parseFmtChunk(b []byte) (Format, error) {
+	le := binary.LittleEndian
+	tag := le.Uint16(b[0:])
+	f := Format{Channels: int(le.Uint16(b[2:])), SampleRate: int(le.Uint32(b[4:]))}
+	blockAlign, bits := int(le.Uint16(b[12:])), int(le.Uint16(b[14:]))
+	if tag == wavTagExtensible {
+		if len(b) < 40 {
+			return Format{}, fmt.Errorf("%w: WAVE_FORMAT_EXTENSIBLE fmt chunk'ı kısa", ErrInvalidWAV)
+		}
+		tag = le.Uint16(b[24:]) // SubFormat GUID'inin ilk iki byte'ı format etiketidir
+	}
+
+	switch {
+	case tag == wavTagPCM && bits == 16:
+		f.SampleFormat = PCM16
+	case tag == wavTagPCM && bits == 24:
+		f.SampleFormat = PCM24
+	case tag == wavTagFloat && bits == 32:
+		f.SampleFormat = Float32
+	default:
+		return Format{}, fmt.Errorf("%w: format etiketi 0x%04x, %d bit", ErrUnsupportedWAV, tag, bits)
+	}
+	if err := f.validate(); err != nil {
+		return Format{}, err
+	}
+	if blockAlign != f.FrameSize() {
+		return Format{}, fmt.Errorf("%w: block align %d, %d kanal × %d bit ile uyuşmuyor", ErrInvalidWAV, blockAlign, f.Channels, bits)
+	}
+	return f, nil
+}
+
+// Format: Dosyanın ses biçimi
+func (r *WAVReader) Format() Format { return r.format }
+
+// Frames: Dosyadaki örnek anı sayısı; boyutu bilinmeyen akışlarda -1
+func (r *WAVReader) Frames() int64 { return r.frames }
+
+// Duration: Dosyanın süresi; boyutu bilinmeyen akışlarda 0
+func (r *WAVReader) Duration() time.Duration {
+	if r.frames < 0 {
+		return 0
+	}
+	return r.format.Duration(r.frames)
+}
+
+// Read: Ses verisini dosyadaki biçimiyle (kanallar iç içe) okur
+func (r *WAVReader) Read(p []byte) (int, error) {
+	return r.data.Read(p)
+}
+
+// ReadPCM16: Ses verisini kanalları koruyarak 16-bit little-endian PCM'e dönüştürüp okur.
+// Sadece tam örnek anları döner; dosya sonundaki yarım örnek anı atılır.
+func (r *WAVReader) ReadPCM16(p []byte) (int, error) {
+	ch := r.format.Channels
+	frames := len(p) / (2 * ch)
+	if frames == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	if r.format.SampleFormat == PCM16 {
+		n, err := io.ReadFull(r.data, p[:frames*2*ch])
+		return r.wholeFrames(n, 2*ch, err)
+	}
+
+	size := frames * r.format.FrameSize()
+	if cap(r.raw) < size {
+		r.raw = make([]byte, size)
+	}
+	n, err := io.ReadFull(r.data, r.raw[:size])
+	n, err = r.wholeFrames(n, r.format.FrameSize(), err)
+	samples := n / (r.format.SampleFormat.BitsPerSample() / 8)
+	for i := 0; i < samples; i++ {
+		var v int16
+		switch r.format.SampleFormat {
+		case PCM24:
+			b := r.raw[3*i:]
+			s := int32(b[0])<<8 | int32(b[1])<<16 | int32(int8(b[2]))<<24 // İşaretiyle 32 bite
+			v = int16(min((int64(s)+1<<15)>>16, math.MaxInt16))           // En yakın 16-bit değere
+		case Float32:
+			f := float64(math.Float32frombits(binary.LittleEndian.Uint32(r.raw[4*i:])))
+			if !math.IsNaN(f) {
+				v = int16(math.Round(math.Max(-1, math.Min(1, f)) * math.MaxInt16))
+			}
+		}
+		binary.LittleEndian.PutUint16(p[2*i:], uint16(v))
+	}
+	return 2 * samples, err
+}
+
+// wholeFrames: Okunan byte sayısını tam örnek anına indirir; kısa okuma dosya sonudur
+func (r *WAVReader) wholeFrames(n, frameSize int, err error) (int, error) {
+	n -= n % frameSize
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+		if n == 0 {
+			err = io.EOF
+		}
+	}
+	return n, err
+}
+
+// --- Yazma ---
+
+// WAVWriter: Ses verisini WAV olarak akış halinde yazar. Hedef io.WriteSeeker ise Close
+// başlıktaki boyutları düzeltir; değilse (pipe, HTTP gövdesi) boyut baştan verilmeli veya
+// başlık "bilinmiyor" olarak işaretlenir (ffmpeg'in pipe çıktısındaki gibi).
+type WAVWriter struct {
+	w       io.Writer
+	format  Format
+	size    int64 // Başlığa yazılan data boyutu; -1: bilinmiyor
+	written int64
+}
+
+// wavHeaderSize: fmt (PCM için 16, float için cbSize ile 18 byte) ve data chunk başlıkları dahil
+func wavHeaderSize(f Format) int64 {
+	if f.SampleFormat == Float32 {
+		return 46
+	}
+	return 44
+}
+
+// NewWAVWriter: Başlığı yazar. dataSize: yazılacak ses verisinin byte uzunluğu; bilinmiyorsa -1
+func NewWAVWriter(w io.Writer, f Format, dataSize int64) (*WAVWriter, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	ww := &WAVWriter{w: w, format: f, size: dataSize}
+	if _, err := w.Write(ww.header(dataSize)); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+func (w *WAVWriter) header(dataSize int64) []byte {
+	f := w.format
+	riffSize, size := uint32(wavUnknownSize), uint32(wavUnknownSize)
+	if dataSize >= 0 {
+		size = uint32(dataSize)
+		riffSize = uint32(wavHeaderSize(f) - 8 + dataSize + dataSize%2)
+	}
+	le := binary.LittleEndian
+	b := make([]byte, 0, wavHeaderSize(f))
+	b = append(b, "RIFF"...)
+	b = le.AppendUint32(b, riffSize)
+	b = append(b, "WAVEfmt "...)
+	tag := uint16(wavTagPCM)
+	if f.SampleFormat == Float32 {
+		b = le.AppendUint32(b, 18)
+		tag = wavTagFloat
+	} else {
+		b = le.AppendUint32(b, 16)
+	}
+	b = le.AppendUint16(b, tag)
+	b = le.AppendUint16(b, uint16(f.Channels))
+	b = le.AppendUint32(b, uint32(f.SampleRate))
+	b = le.AppendUint32(b, uint32(f.BytesPerSecond()))
+	b = le.AppendUint16(b, uint16(f.FrameSize()))
+	b = le.AppendUint16(b, uint16(f.SampleFormat.BitsPerSample()))
+	if f.SampleFormat == Float32 {
+		b = le.AppendUint16(b, 0) // cbSize
+	}
+	b = append(b, "data"...)
+	return le.AppendUint32(b, size)
+}
+
+// Write: Format'taki ses verisini (kanallar iç içe) ekler
+func (w *WAVWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Close: Tek sayıda byte yazıldıysa RIFF hizalaması için dolgu ekler ve hedef seekable ise
+// başlıktaki boyutları düzeltir. Alttaki yazıcıyı kapatmaz.
+func (w *WAVWriter) Close() error {
+	if w.written%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	if w.size == w.written {
+		return nil
+	}
+	ws, ok := w.w.(io.WriteSeeker)
+	if !ok {
+		if w.size < 0 {
+			return nil // Başlık "bilinmiyor" diyor; okuyucular dosya sonuna kadar okur
+		}
+		return fmt.Errorf("WAV başlığı %d byte bildiriyor, %d byte yazıldı", w.size, w.written)
+	}
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := ws.Seek(end-w.written-w.written%2-wavHeaderSize(w.format), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := ws.Write(w.header(w.written)); err != nil {
+		return err
+	}
+	_, err = ws.Seek(end, io.SeekStart)
+	return err
+}
+
+// EncodeWAV: Bellekteki ses verisini tek seferde WAV'a sarar (kısa segmentler için)
+func EncodeWAV(f Format, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(wavHeaderSize(f)) + len(data) + 1)
+	w, err := NewWAVWriter(&buf, f, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
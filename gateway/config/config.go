@@ -0,0 +1,514 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config: Gateway'in çalışma zamanı ayarları.
+// Öncelik sırası: varsayılanlar < JSON dosyası < ortam değişkenleri < komut satırı bayrakları
+type Config struct {
+	Port            string   `json:"port"`
+	DBPath          string   `json:"db_path"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"` // Kapanışta canlı oturumların boşaltılması için süre
+
+	LogLevel  string `json:"log_level"`  // debug | info | warn | error
+	LogFormat string `json:"log_format"` // json | text
+
+	// OpenTelemetry
+	TraceExporter    string  `json:"trace_exporter"`     // none | stdout | otlp
+	TraceEndpoint    string  `json:"trace_endpoint"`     // OTLP/HTTP adresi (boşsa OTEL_EXPORTER_OTLP_ENDPOINT)
+	TraceSampleRatio float64 `json:"trace_sample_ratio"` // 0-1 arası örnekleme oranı
+
+	// Ses akışı (SampleRate Hz, 16-bit mono PCM)
+	PacketSize int    `json:"packet_size"` // VAD çerçeve boyutu (byte)
+	FFmpegPath string `json:"ffmpeg_path"` // Sıkıştırılmış (Opus) akışları çözen ffmpeg
+	// Varsayılan segmentasyon kuralları (alanlar üst seviyede: "min_segment", "pre_roll" ...);
+	// oturum start mesajıyla geçersiz kılabilir
+	SegmentationPolicy
+	// Bağlantısı kopan oturumun aynı ID ile devam ettirilebileceği süre (0: devam ettirme kapalı)
+	ResumeGrace Duration `json:"resume_grace"`
+	// Sıradaki segmentin sonucu, worker işe başladıktan sonra bu süre içinde gelmezse sonraki segmentlerin
+	// sonuçları onu beklemeden gönderilir
+	ReorderTimeout Duration `json:"reorder_timeout"`
+	// Süren konuşmanın bu kadar yeni sesi biriktikçe ara transkripsiyonu gönderilir (0: kapalı)
+	InterimInterval Duration `json:"interim_interval"`
+	// İstemciye tek bir mesajın yazılması için verilen süre; aşılırsa bağlantı kapatılır
+	WriteTimeout Duration `json:"write_timeout"`
+
+	// Segment analizi: tüm oturumlar aynı sınırlı worker havuzunu paylaşır
+	SegmentWorkers    int `json:"segment_workers"`     // Aynı anda analiz edilen en fazla segment
+	SessionQueueLimit int `json:"session_queue_limit"` // Bir oturumun bekleyen + işlenen en fazla segmenti
+	GlobalQueueLimit  int `json:"global_queue_limit"`  // Worker bekleyen en fazla segment (tüm oturumlar)
+
+	// Kayıt (enrollment) için yüklenen ses dosyaları
+	MaxUploadBytes int      `json:"max_upload_bytes"` // Yüklenen dosyanın üst sınırı
+	MaxUploadAudio Duration `json:"max_upload_audio"` // Çözülmüş sesin üst sınırı (sıkıştırma bombalarına karşı)
+	DecodeTimeout  Duration `json:"decode_timeout"`   // Bir dosyanın çözülmesi için verilen süre
+
+	// Analiz için yüklenen kayıtlar (çözme zaman aşımı decode_timeout)
+	// Çözülen ses analiz bitene kadar bellekte tutulur; en kötü bellek kullanımı yaklaşık
+	// max_concurrent_recordings × max_recording_audio × 32 KB/s'dir
+	MaxRecordingBytes       int      `json:"max_recording_bytes"`       // Yüklenen kayıt dosyasının üst sınırı
+	MaxRecordingAudio       Duration `json:"max_recording_audio"`       // Çözülmüş sesin (tüm kanallar toplamı) üst sınırı
+	MaxConcurrentRecordings int      `json:"max_concurrent_recordings"` // Aynı anda çözülen veya analiz edilen en fazla kayıt
+
+	// Python servisleri
+	WhisperServiceURL string   `json:"whisper_service_url"`
+	AudioServiceURL   string   `json:"audio_service_url"`
+	TextServiceURL    string   `json:"text_service_url"`
+	ServiceTimeout    Duration `json:"service_timeout"`
+	HealthTimeout     Duration `json:"health_timeout"` // /readyz kontrollerinde servis başına zaman aşımı
+
+	// Servis başına tekrar deneme ve devre kesici ayarları (sadece dosyadan)
+	ServicePolicies ServicePolicies `json:"service_policies"`
+}
+
+// SegmentationPolicy: Canlı sesin VAD ile segmentlere bölünme kuralları
+type SegmentationPolicy struct {
+	VADAggressiveness int      `json:"vad_aggressiveness"` // webrtcvad modu (0: en hoşgörülü, 3: en agresif)
+	SilenceHangover   Duration `json:"silence_hangover"`   // Konuşmayı bitmiş saydıran kesintisiz sessizlik
+	MinSegment        Duration `json:"min_segment"`        // Bir segmentin analize gönderilmesi için gereken minimum süre
+	MaxSegment        Duration `json:"max_segment"`        // Sessizlik gelmese de segmentin en sessiz yakın çerçeveden kesildiği süre
+	PreRoll           Duration `json:"pre_roll"`           // Konuşma başlamadan önceki, segmentin başına eklenen ses
+}
+
+// MaxPreRoll: Pre-roll için üst sınır; daha fazlası kelime başlarını korumaya katkı sağlamaz
+const MaxPreRoll = time.Second
+
+// SampleRate: Gateway'in VAD'e ve servislere verdiği PCM'in hızı. Whisper servisi (whisperX/main.py)
+// ham PCM'i hız bilgisi almadan bu hızda yorumladığı için ayarlanamaz; istemci sesi buna dönüştürülür.
+const SampleRate = 16000
+
+// Validate: Tüm hataları birlikte döner
+func (p SegmentationPolicy) Validate() error {
+	var errs []error
+	if p.VADAggressiveness < 0 || p.VADAggressiveness > 3 {
+		errs = append(errs, fmt.Errorf("vad_aggressiveness 0-3 arasında olmalı, %d verildi", p.VADAggressiveness))
+	}
+	if p.SilenceHangover.Duration <= 0 {
+		errs = append(errs, errors.New("silence_hangover pozitif olmalı"))
+	}
+	if p.MinSegment.Duration <= 0 {
+		errs = append(errs, errors.New("min_segment pozitif olmalı"))
+	}
+	if p.MaxSegment.Duration <= p.MinSegment.Duration {
+		errs = append(errs, fmt.Errorf("max_segment (%s) min_segment'ten (%s) uzun olmalı", p.MaxSegment, p.MinSegment))
+	}
+	if p.PreRoll.Duration < 0 || p.PreRoll.Duration > MaxPreRoll {
+		errs = append(errs, fmt.Errorf("pre_roll 0-%s arasında olmalı, %s verildi", MaxPreRoll, p.PreRoll))
+	}
+	return errors.Join(errs...)
+}
+
+// ServicePolicy: Bir Python servisi için tekrar deneme ve devre kesici ayarları
+type ServicePolicy struct {
+	MaxAttempts      int      `json:"max_attempts"`      // İlk deneme dahil; sadece idempotent çağrılar tekrar denenir
+	BaseDelay        Duration `json:"base_delay"`        // Üstel bekleme tabanı (jitter uygulanır)
+	MaxDelay         Duration `json:"max_delay"`         // Tek bir bekleme için üst sınır
+	BreakerThreshold int      `json:"breaker_threshold"` // Devreyi açan ardışık hata sayısı (0: kapalı)
+	BreakerCooldown  Duration `json:"breaker_cooldown"`  // Devre açıkken isteklerin hemen reddedildiği süre
+}
+
+type ServicePolicies struct {
+	Whisper ServicePolicy `json:"whisper"`
+	Audio   ServicePolicy `json:"audio"`
+	Text    ServicePolicy `json:"text"`
+}
+
+// C: Uygulamanın kullandığı etkin yapılandırma (main içinde Load ile doldurulur)
+var C = Default()
+
+// Default: Varsayılan ayarlar. Koddaki sabitlerden taşınan değerler (port, packet_size, servis adresleri,
+// vad_aggressiveness, min_segment vb.) eskisiyle aynıdır; silence_hangover, pre_roll, max_segment ve
+// interim_interval ise yeni davranışlardır ve varsayılan olarak açıktır.
+func Default() *Config {
+	return &Config{
+		Port:             ":8080",
+		DBPath:           "db.sqlite",
+		ShutdownTimeout:  Duration{30 * time.Second},
+		LogLevel:         "info",
+		LogFormat:        "json",
+		TraceExporter:    "none",
+		TraceSampleRatio: 1,
+		PacketSize:       640,
+		FFmpegPath:       "ffmpeg",
+		SegmentationPolicy: SegmentationPolicy{
+			VADAggressiveness: 3,
+			SilenceHangover:   Duration{500 * time.Millisecond},
+			MinSegment:        Duration{3 * time.Second},
+			MaxSegment:        Duration{30 * time.Second},
+			PreRoll:           Duration{200 * time.Millisecond},
+		},
+		ResumeGrace:             Duration{30 * time.Second},
+		ReorderTimeout:          Duration{30 * time.Second},
+		InterimInterval:         Duration{3 * time.Second},
+		WriteTimeout:            Duration{10 * time.Second},
+		SegmentWorkers:          4,
+		SessionQueueLimit:       8,
+		GlobalQueueLimit:        64,
+		MaxUploadBytes:          10 << 20,
+		MaxUploadAudio:          Duration{2 * time.Minute},
+		DecodeTimeout:           Duration{30 * time.Second},
+		MaxRecordingBytes:       100 << 20,
+		MaxRecordingAudio:       Duration{20 * time.Minute},
+		MaxConcurrentRecordings: 2,
+		WhisperServiceURL:       "http://localhost:5000/",
+		AudioServiceURL:         "http://localhost:5001/",
+		TextServiceURL:          "http://localhost:5002/",
+		ServiceTimeout:          Duration{60 * time.Second},
+		HealthTimeout:           Duration{2 * time.Second},
+		ServicePolicies: ServicePolicies{
+			// Whisper CPU üzerinde yavaş: tek tekrar yeterli
+			Whisper: ServicePolicy{MaxAttempts: 2, BaseDelay: Duration{500 * time.Millisecond}, MaxDelay: Duration{5 * time.Second}, BreakerThreshold: 5, BreakerCooldown: Duration{30 * time.Second}},
+			Audio:   ServicePolicy{MaxAttempts: 3, BaseDelay: Duration{200 * time.Millisecond}, MaxDelay: Duration{2 * time.Second}, BreakerThreshold: 5, BreakerCooldown: Duration{15 * time.Second}},
+			Text:    ServicePolicy{MaxAttempts: 3, BaseDelay: Duration{200 * time.Millisecond}, MaxDelay: Duration{2 * time.Second}, BreakerThreshold: 5, BreakerCooldown: Duration{15 * time.Second}},
+		},
+	}
+}
+
+// Load: Varsayılanları, dosyayı (-config veya GATEWAY_CONFIG), ortam değişkenlerini ve bayrakları
+// sırasıyla uygular, sonucu doğrular ve C'ye atar.
+func Load(args []string) (*Config, error) {
+	// Bayraklar geçici bir kopyaya parse edilir; sadece açıkça verilenler en son uygulanır.
+	scratch := Default()
+	fs := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("GATEWAY_CONFIG"), "JSON yapılandırma dosyası")
+	for _, f := range scratch.fields() {
+		fs.Var(f.value, f.flag, fmt.Sprintf("%s (env: %s)", f.usage, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := cfg.fieldMap()
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := f.value.Set(v); err != nil {
+				return nil, fmt.Errorf("%s geçersiz: %w", f.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := fields[fl.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := f.value.Set(fl.Value.String()); err != nil {
+			flagErr = fmt.Errorf("-%s geçersiz: %w", fl.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	C = cfg
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("yapılandırma dosyası okunamadı: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("yapılandırma dosyası (%s) çözümlenemedi: %w", path, err)
+	}
+	return nil
+}
+
+// Validate: Ayarların tutarlılığını kontrol eder ve servis adreslerini normalize eder
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Port); err != nil {
+		errs = append(errs, fmt.Errorf("port %q geçersiz (örn. \":8080\"): %v", c.Port, err))
+	}
+	if c.DBPath == "" {
+		errs = append(errs, errors.New("db_path boş olamaz"))
+	}
+	if c.FFmpegPath == "" {
+		errs = append(errs, errors.New("ffmpeg_path boş olamaz"))
+	}
+
+	// webrtcvad sadece 10/20/30 ms çerçeveleri destekler
+	if ms := c.PacketSize * 1000 / c.BytesPerSecond(); c.PacketSize*1000%c.BytesPerSecond() != 0 || (ms != 10 && ms != 20 && ms != 30) {
+		errs = append(errs, fmt.Errorf("packet_size %d, %d Hz için 10/20/30 ms'lik bir çerçeve değil", c.PacketSize, SampleRate))
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level %q geçersiz (debug, info, warn, error)", c.LogLevel))
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log_format %q geçersiz (json, text)", c.LogFormat))
+	}
+	switch c.TraceExporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("trace_exporter %q geçersiz (none, stdout, otlp)", c.TraceExporter))
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("trace_sample_ratio %v 0 ile 1 arasında olmalı", c.TraceSampleRatio))
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout pozitif olmalı"))
+	}
+	if c.ResumeGrace.Duration < 0 {
+		errs = append(errs, errors.New("resume_grace negatif olamaz"))
+	}
+	if c.ReorderTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("reorder_timeout pozitif olmalı"))
+	}
+	if c.InterimInterval.Duration < 0 {
+		errs = append(errs, errors.New("interim_interval negatif olamaz"))
+	}
+	if c.WriteTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("write_timeout pozitif olmalı"))
+	}
+	if c.SegmentWorkers <= 0 {
+		errs = append(errs, fmt.Errorf("segment_workers %d pozitif olmalı", c.SegmentWorkers))
+	}
+	if c.SessionQueueLimit <= 0 {
+		errs = append(errs, fmt.Errorf("session_queue_limit %d pozitif olmalı", c.SessionQueueLimit))
+	}
+	if c.GlobalQueueLimit <= 0 {
+		errs = append(errs, fmt.Errorf("global_queue_limit %d pozitif olmalı", c.GlobalQueueLimit))
+	}
+	if c.MaxUploadBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_upload_bytes %d pozitif olmalı", c.MaxUploadBytes))
+	}
+	if c.MaxUploadAudio.Duration <= 0 {
+		errs = append(errs, errors.New("max_upload_audio pozitif olmalı"))
+	}
+	if c.DecodeTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("decode_timeout pozitif olmalı"))
+	}
+	if c.MaxRecordingBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_recording_bytes %d pozitif olmalı", c.MaxRecordingBytes))
+	}
+	if c.MaxRecordingAudio.Duration <= 0 {
+		errs = append(errs, errors.New("max_recording_audio pozitif olmalı"))
+	}
+	if c.MaxConcurrentRecordings <= 0 {
+		errs = append(errs, fmt.Errorf("max_concurrent_recordings %d pozitif olmalı", c.MaxConcurrentRecordings))
+	}
+	if err := c.SegmentationPolicy.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ServiceTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("service_timeout pozitif olmalı"))
+	}
+	if c.HealthTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("health_timeout pozitif olmalı"))
+	}
+
+	for _, p := range []struct {
+		name   string
+		policy ServicePolicy
+	}{
+		{"whisper", c.ServicePolicies.Whisper},
+		{"audio", c.ServicePolicies.Audio},
+		{"text", c.ServicePolicies.Text},
+	} {
+		if err := p.policy.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("service_policies.%s: %w", p.name, err))
+		}
+	}
+
+	for _, svc := range []struct {
+		name string
+		url  *string
+	}{
+		{"whisper_service_url", &c.WhisperServiceURL},
+		{"audio_service_url", &c.AudioServiceURL},
+		{"text_service_url", &c.TextServiceURL},
+	} {
+		name, u := svc.name, svc.url
+		parsed, err := url.Parse(*u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("%s %q geçerli bir http(s) adresi değil", name, *u))
+			continue
+		}
+		// Servis çağrıları endpoint adını doğrudan sona ekliyor
+		if !strings.HasSuffix(*u, "/") {
+			*u += "/"
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p ServicePolicy) validate() error {
+	var errs []error
+	if p.MaxAttempts < 1 {
+		errs = append(errs, errors.New("max_attempts en az 1 olmalı"))
+	}
+	if p.BaseDelay.Duration < 0 || p.MaxDelay.Duration < p.BaseDelay.Duration {
+		errs = append(errs, errors.New("base_delay negatif olamaz ve max_delay'den büyük olamaz"))
+	}
+	if p.BreakerThreshold < 0 {
+		errs = append(errs, errors.New("breaker_threshold negatif olamaz"))
+	}
+	if p.BreakerThreshold > 0 && p.BreakerCooldown.Duration <= 0 {
+		errs = append(errs, errors.New("breaker_cooldown pozitif olmalı"))
+	}
+	return errors.Join(errs...)
+}
+
+// BytesPerSecond: 16-bit mono PCM için saniyedeki byte sayısı
+func (c *Config) BytesPerSecond() int {
+	return SampleRate * 2
+}
+
+// DurationBytes: Sürenin PCM byte karşılığı
+func (c *Config) DurationBytes(d time.Duration) int {
+	return int(d.Seconds() * float64(c.BytesPerSecond()))
+}
+
+// InterimBytes: InterimInterval süresinin byte karşılığı (0: ara transkripsiyon kapalı)
+func (c *Config) InterimBytes() int {
+	return c.DurationBytes(c.InterimInterval.Duration)
+}
+
+// String: Başlangıçta loglanan etkin yapılandırma
+func (c *Config) String() string {
+	out, _ := json.MarshalIndent(c, "", "  ")
+	return string(out)
+}
+
+// --- Dosya / ortam / bayrak eşlemesi ---
+
+type field struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		{"port", "GATEWAY_PORT", "HTTP dinleme adresi", (*stringValue)(&c.Port)},
+		{"db", "GATEWAY_DB_PATH", "SQLite veritabanı dosyası", (*stringValue)(&c.DBPath)},
+		{"shutdown-timeout", "GATEWAY_SHUTDOWN_TIMEOUT", "Kapanışta oturumları bekleme süresi (örn. 30s)", &c.ShutdownTimeout},
+		{"log-level", "GATEWAY_LOG_LEVEL", "Log seviyesi (debug, info, warn, error)", (*stringValue)(&c.LogLevel)},
+		{"log-format", "GATEWAY_LOG_FORMAT", "Log formatı (json, text)", (*stringValue)(&c.LogFormat)},
+		{"trace-exporter", "GATEWAY_TRACE_EXPORTER", "Trace exporter (none, stdout, otlp)", (*stringValue)(&c.TraceExporter)},
+		{"trace-endpoint", "GATEWAY_TRACE_ENDPOINT", "OTLP/HTTP trace adresi", (*stringValue)(&c.TraceEndpoint)},
+		{"trace-sample-ratio", "GATEWAY_TRACE_SAMPLE_RATIO", "Trace örnekleme oranı (0-1)", (*floatValue)(&c.TraceSampleRatio)},
+		{"packet-size", "GATEWAY_PACKET_SIZE", "VAD çerçeve boyutu (byte)", (*intValue)(&c.PacketSize)},
+		{"ffmpeg", "GATEWAY_FFMPEG_PATH", "ffmpeg çalıştırılabilir dosyası", (*stringValue)(&c.FFmpegPath)},
+		{"vad-aggressiveness", "GATEWAY_VAD_AGGRESSIVENESS", "Varsayılan VAD modu (0-3)", (*intValue)(&c.VADAggressiveness)},
+		{"silence-hangover", "GATEWAY_SILENCE_HANGOVER", "Segmenti bitiren sessizlik süresi (örn. 500ms)", &c.SilenceHangover},
+		{"pre-roll", "GATEWAY_PRE_ROLL", "Segment başına eklenen konuşma öncesi ses (örn. 200ms)", &c.PreRoll},
+		{"min-segment", "GATEWAY_MIN_SEGMENT", "Minimum segment süresi (örn. 3s)", &c.MinSegment},
+		{"max-segment", "GATEWAY_MAX_SEGMENT", "Maksimum segment süresi (örn. 30s)", &c.MaxSegment},
+		{"resume-grace", "GATEWAY_RESUME_GRACE", "Kopan oturumun devam ettirilebileceği süre (0: kapalı)", &c.ResumeGrace},
+		{"reorder-timeout", "GATEWAY_REORDER_TIMEOUT", "Sıradaki segment sonucunun en fazla beklenme süresi", &c.ReorderTimeout},
+		{"interim-interval", "GATEWAY_INTERIM_INTERVAL", "Ara transkripsiyon aralığı (0: kapalı)", &c.InterimInterval},
+		{"write-timeout", "GATEWAY_WRITE_TIMEOUT", "İstemciye mesaj yazma zaman aşımı (örn. 10s)", &c.WriteTimeout},
+		{"segment-workers", "GATEWAY_SEGMENT_WORKERS", "Aynı anda analiz edilen en fazla segment", (*intValue)(&c.SegmentWorkers)},
+		{"session-queue-limit", "GATEWAY_SESSION_QUEUE_LIMIT", "Oturum başına bekleyen en fazla segment", (*intValue)(&c.SessionQueueLimit)},
+		{"global-queue-limit", "GATEWAY_GLOBAL_QUEUE_LIMIT", "Tüm oturumlarda worker bekleyen en fazla segment", (*intValue)(&c.GlobalQueueLimit)},
+		{"max-upload-bytes", "GATEWAY_MAX_UPLOAD_BYTES", "Kayıt için yüklenen ses dosyasının üst sınırı (byte)", (*intValue)(&c.MaxUploadBytes)},
+		{"max-upload-audio", "GATEWAY_MAX_UPLOAD_AUDIO", "Yüklenen dosyanın çözülmüş ses süresi sınırı (örn. 2m)", &c.MaxUploadAudio},
+		{"decode-timeout", "GATEWAY_DECODE_TIMEOUT", "Yüklenen dosyayı çözme zaman aşımı (örn. 30s)", &c.DecodeTimeout},
+		{"max-recording-bytes", "GATEWAY_MAX_RECORDING_BYTES", "Analiz için yüklenen kayıt dosyasının üst sınırı (byte)", (*intValue)(&c.MaxRecordingBytes)},
+		{"max-recording-audio", "GATEWAY_MAX_RECORDING_AUDIO", "Yüklenen kaydın tüm kanallar toplamı ses süresi sınırı (örn. 20m)", &c.MaxRecordingAudio},
+		{"max-concurrent-recordings", "GATEWAY_MAX_CONCURRENT_RECORDINGS", "Aynı anda analiz edilen en fazla yüklenmiş kayıt", (*intValue)(&c.MaxConcurrentRecordings)},
+		{"whisper-url", "GATEWAY_WHISPER_URL", "Whisper servis adresi", (*stringValue)(&c.WhisperServiceURL)},
+		{"audio-url", "GATEWAY_AUDIO_URL", "Audio servis adresi", (*stringValue)(&c.AudioServiceURL)},
+		{"text-url", "GATEWAY_TEXT_URL", "Text servis adresi", (*stringValue)(&c.TextServiceURL)},
+		{"service-timeout", "GATEWAY_SERVICE_TIMEOUT", "Servis istek zaman aşımı (örn. 60s)", &c.ServiceTimeout},
+		{"health-timeout", "GATEWAY_HEALTH_TIMEOUT", "Hazırlık kontrolü zaman aşımı (örn. 2s)", &c.HealthTimeout},
+	}
+}
+
+func (c *Config) fieldMap() map[string]field {
+	m := make(map[string]field)
+	for _, f := range c.fields() {
+		m[f.flag] = f
+	}
+	return m
+}
+
+type stringValue string
+
+func (s *stringValue) String() string     { return string(*s) }
+func (s *stringValue) Set(v string) error { *s = stringValue(v); return nil }
+
+type intValue int
+
+func (i *intValue) String() string { return strconv.Itoa(int(*i)) }
+func (i *intValue) Set(v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*i = intValue(n)
+	return nil
+}
+
+type floatValue float64
+
+func (f *floatValue) String() string { return strconv.FormatFloat(float64(*f), 'g', -1, 64) }
+func (f *floatValue) Set(v string) error {
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return err
+	}
+	*f = floatValue(n)
+	return nil
+}
+
+// Duration: JSON'da "3s", "500ms" gibi yazılabilen süre
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) Set(v string) error {
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("süre metin olmalı (örn. \"3s\"): %w", err)
+	}
+	return d.Set(s)
+}
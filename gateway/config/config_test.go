@@ -0,0 +1,188 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig: JSON yapılandırma dosyası yazar ve yolunu döner
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// load: Load'u çağırır; Load C'yi değiştirdiği için eski değer test sonunda geri yüklenir
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	prev := C
+	t.Cleanup(func() { C = prev })
+	return Load(args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `{"port": ":1000", "db_path": "file.sqlite", "segment_workers": 2, "min_segment": "2s", "interim_interval": "1s"}`)
+	t.Setenv("GATEWAY_CONFIG", path)
+	t.Setenv("GATEWAY_PORT", ":2000")
+	t.Setenv("GATEWAY_SEGMENT_WORKERS", "3")
+	t.Setenv("GATEWAY_INTERIM_INTERVAL", "2s")
+
+	cfg, err := load(t, "-port", ":3000", "-interim-interval", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"default when nothing overrides", cfg.LogLevel, "info"},
+		{"file over default", cfg.DBPath, "file.sqlite"},
+		{"file duration over default", cfg.MinSegment.Duration, 2 * time.Second},
+		{"env over file", cfg.SegmentWorkers, 3},
+		{"flag over env and file", cfg.Port, ":3000"},
+		{"flag zero value still applies", cfg.InterimInterval.Duration, time.Duration(0)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+	if C != cfg {
+		t.Error("Load did not install the loaded configuration as C")
+	}
+}
+
+func TestLoadFlagOverridesConfigEnv(t *testing.T) {
+	t.Setenv("GATEWAY_CONFIG", writeConfig(t, `{"port": ":1000"}`))
+	path := writeConfig(t, `{"port": ":4000"}`)
+
+	cfg, err := load(t, "-config", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != ":4000" {
+		t.Errorf("port = %q, want the -config file's :4000", cfg.Port)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file string // Boşsa dosya verilmez
+		env  map[string]string
+		args []string
+		want string // Hata mesajında geçmesi gereken metin
+	}{
+		{"unknown file field", `{"sample_rate": 8000}`, nil, nil, "sample_rate"},
+		{"malformed file", `{"port": `, nil, nil, "çözümlenemedi"},
+		{"numeric duration in file", `{"min_segment": 3}`, nil, nil, "süre metin olmalı"},
+		{"bad duration in file", `{"min_segment": "3 seconds"}`, nil, nil, `"3 seconds"`},
+		{"bad duration in env", "", map[string]string{"GATEWAY_MIN_SEGMENT": "soon"}, nil, "GATEWAY_MIN_SEGMENT geçersiz"},
+		{"bad int in env", "", map[string]string{"GATEWAY_SEGMENT_WORKERS": "four"}, nil, "GATEWAY_SEGMENT_WORKERS geçersiz"},
+		{"bad duration flag", "", nil, []string{"-max-segment", "long"}, "max-segment"},
+		{"unknown flag", "", nil, []string{"-sample-rate", "8000"}, "sample-rate"},
+		{"invalid after merge", `{"min_segment": "40s"}`, nil, nil, "max_segment"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GATEWAY_CONFIG", "")
+			if tc.file != "" {
+				t.Setenv("GATEWAY_CONFIG", writeConfig(t, tc.file))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			prev := C
+			_, err := load(t, tc.args...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Load error = %v, want one mentioning %q", err, tc.want)
+			}
+			if C != prev {
+				t.Error("a failed Load replaced C")
+			}
+		})
+	}
+}
+
+func TestDurationParsing(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{`"500ms"`, 500 * time.Millisecond, false},
+		{`"1m30s"`, 90 * time.Second, false},
+		{`"0"`, 0, false},
+		{`"-2s"`, -2 * time.Second, false}, // Aralık kontrolü Validate'te
+		{`3`, 0, true},
+		{`"3"`, 0, true},
+		{`"yarım saat"`, 0, true},
+	} {
+		var d Duration
+		err := d.UnmarshalJSON([]byte(tc.in))
+		if (err != nil) != tc.wantErr || (err == nil && d.Duration != tc.want) {
+			t.Errorf("UnmarshalJSON(%s) = %v, %v; want %v (error: %v)", tc.in, d.Duration, err, tc.want, tc.wantErr)
+		}
+	}
+
+	out, err := Duration{1500 * time.Millisecond}.MarshalJSON()
+	if err != nil || string(out) != `"1.5s"` {
+		t.Errorf("MarshalJSON = %s, %v; want \"1.5s\"", out, err)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate func(c *Config)
+		want   []string
+	}{
+		{"port without colon", func(c *Config) { c.Port = "8080" }, []string{"port"}},
+		{"empty db path", func(c *Config) { c.DBPath = "" }, []string{"db_path"}},
+		{"packet not a VAD frame", func(c *Config) { c.PacketSize = 700 }, []string{"packet_size 700"}},
+		{"unknown log level", func(c *Config) { c.LogLevel = "verbose" }, []string{"log_level"}},
+		{"unknown trace exporter", func(c *Config) { c.TraceExporter = "jaeger" }, []string{"trace_exporter"}},
+		{"sample ratio above 1", func(c *Config) { c.TraceSampleRatio = 1.5 }, []string{"trace_sample_ratio"}},
+		{"negative resume grace", func(c *Config) { c.ResumeGrace.Duration = -time.Second }, []string{"resume_grace"}},
+		{"zero write timeout", func(c *Config) { c.WriteTimeout.Duration = 0 }, []string{"write_timeout"}},
+		{"no workers", func(c *Config) { c.SegmentWorkers = 0 }, []string{"segment_workers"}},
+		{"min segment not below max", func(c *Config) { c.MinSegment = c.MaxSegment }, []string{"max_segment"}},
+		{"pre-roll above limit", func(c *Config) { c.PreRoll.Duration = 2 * time.Second }, []string{"pre_roll"}},
+		{"vad mode out of range", func(c *Config) { c.VADAggressiveness = 4 }, []string{"vad_aggressiveness"}},
+		{"service policy", func(c *Config) { c.ServicePolicies.Text.MaxAttempts = 0 }, []string{"service_policies.text", "max_attempts"}},
+		{"breaker without cooldown", func(c *Config) { c.ServicePolicies.Audio.BreakerCooldown.Duration = 0 }, []string{"service_policies.audio", "breaker_cooldown"}},
+		{"non-http service url", func(c *Config) { c.WhisperServiceURL = "ftp://whisper" }, []string{"whisper_service_url"}},
+		{"all errors reported together", func(c *Config) {
+			c.SegmentWorkers, c.LogFormat, c.MaxRecordingBytes = -1, "xml", 0
+		}, []string{"segment_workers", "log_format", "max_recording_bytes"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Default()
+			tc.mutate(c)
+			err := c.Validate()
+			if err == nil {
+				t.Fatal("Validate accepted the configuration")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDefaultsAndNormalizesURLs(t *testing.T) {
+	c := Default()
+	c.TextServiceURL = "http://text:5002"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("defaults rejected: %v", err)
+	}
+	if c.TextServiceURL != "http://text:5002/" {
+		t.Errorf("text_service_url = %q, want a trailing slash for endpoint concatenation", c.TextServiceURL)
+	}
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"context"
+	"gateway/config"
 	"gateway/models"
-	"log"
+	"log/slog"
+	"os"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -14,18 +17,43 @@ var DB *gorm.DB
 func Init() {
 	var err error
 	// GORM ile SQLite bağlantısı
-	DB, err = gorm.Open(sqlite.Open(models.DBName), &gorm.Config{
+	DB, err = gorm.Open(sqlite.Open(config.C.DBPath), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Error), // Sadece hataları logla, konsolu kirletme
 	})
 	if err != nil {
-		log.Fatal("DB Bağlantı hatası:", err)
+		slog.Error("database connection failed", "path", config.C.DBPath, "error", err)
+		os.Exit(1)
+	}
+
+	if err := registerTracing(DB); err != nil {
+		slog.Error("database tracing setup failed", "error", err)
+		os.Exit(1)
 	}
 
 	// Tabloları otomatik oluştur veya güncelle
 	err = DB.AutoMigrate(&models.User{}, &models.Record{}, &models.Segment{})
 	if err != nil {
-		log.Fatal("Migrasyon hatası:", err)
+		slog.Error("database migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("database ready", "path", config.C.DBPath)
+}
+
+// Close: Alttaki SQLite bağlantısını kapatır (kapanış sırasında en son çağrılır)
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
+}
 
-	log.Println("Veritabanı hazır (GORM).")
+// Ping: SQLite bağlantısının kullanılabilir olduğunu doğrular (hazırlık kontrolü için)
+func Ping(ctx context.Context) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
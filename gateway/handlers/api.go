@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gateway/audio"
+	"gateway/config"
 	"gateway/database"
+	"gateway/logging"
 	"gateway/models"
 	"gateway/services"
-	"io"
+	"log/slog"
 	"net/http"
 )
 
@@ -24,14 +29,23 @@ func HandleGetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST /api/record_user
-func HandleRecordUser(w http.ResponseWriter, r *http.Request) {
-	// 1. Form Verilerini Al
-	r.ParseMultipartForm(10 << 20) // 10MB limit
+func (h *Handler) HandleRecordUser(w http.ResponseWriter, r *http.Request) {
+	// 1. Form Verilerini Al (gövde max_upload_bytes ile sınırlı)
+	r.Body = http.MaxBytesReader(w, r.Body, int64(config.C.MaxUploadBytes))
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Dosya çok büyük (en fazla %d byte)", config.C.MaxUploadBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Form okunamadı", 400)
+		return
+	}
 
 	name := r.FormValue("name")
 	surname := r.FormValue("surname")
 
-	// Frontend'den gelen dosya (muhtemelen 'blob' veya .webm uzantılı)
+	// Frontend'den gelen dosya (MediaRecorder'dan WebM/Ogg veya WAV; uzantıya değil içeriğe bakılır)
 	file, _, err := r.FormFile("voice_record_file")
 	if err != nil {
 		http.Error(w, "Dosya alınamadı", 400)
@@ -39,21 +53,23 @@ func HandleRecordUser(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// 2. WebM Dosyasını Belleğe Oku
-	webmData, err := io.ReadAll(file)
-	if err != nil {
-		http.Error(w, "Dosya okunamadı", 500)
-		return
+	// 2-3. Biçimi tanı ve mono PCM'e çöz: WAV Go içinde, diğer biçimler ffmpeg ile
+	// (süre ve çıkış boyutu sınırlı), sonra analiz servisinin beklediği WAV'a sar
+	decodeCtx, cancel := context.WithTimeout(r.Context(), config.C.DecodeTimeout.Duration)
+	defer cancel()
+	pcm, format, err := h.decoders.Decode(decodeCtx, file, audio.DecodeOptions{
+		SampleRate: config.SampleRate,
+		MaxBytes:   int64(config.C.DurationBytes(config.C.MaxUploadAudio.Duration)),
+	})
+	if err == nil && len(pcm) == 0 {
+		err = errors.New("dosyada ses yok")
 	}
-
-	// 3. Format Dönüşümü (WebM -> WAV)
-	// Bu işlem sunucuda FFmpeg kurulu olmasını gerektirir.
-	wavData, err := services.ConvertWebMToWav(webmData)
 	if err != nil {
-		fmt.Println("Dönüşüm Hatası:", err) // Logla
-		http.Error(w, "Ses formatı dönüştürülemedi (FFmpeg hatası)", 500)
+		slog.Warn("enrollment audio decode failed", "format", format, "error", err)
+		http.Error(w, "Ses dosyası çözülemedi: "+err.Error(), decodeStatus(err))
 		return
 	}
+	wavData, _ := audio.EncodeWAV(audio.Format{SampleFormat: audio.PCM16, SampleRate: config.SampleRate, Channels: 1}, pcm)
 
 	// 4. Kullanıcıyı Veritabanına Kaydet
 	// Dosyayı diske kaydetmediğimiz için VoicePath boş veya sembolik olabilir.
@@ -69,12 +85,13 @@ func HandleRecordUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 5. Analyze Servisine (Identificate) Gönder
-	err = services.CallIdentificateService(user.ID, wavData)
+	ctx := logging.WithLogger(r.Context(), slog.With("user_id", user.ID))
+	err = h.svc.Enroller.Enroll(ctx, user.ID, wavData)
 	if err != nil {
 		// Kullanıcı oluştu ama ses gönderilemedi.
 		// Duruma göre DB'den silme işlemi (rollback) yapılabilir veya sadece hata dönülür.
-		fmt.Printf("Analyze Service Hatası (User ID: %d): %v\n", user.ID, err)
-		http.Error(w, "Kullanıcı oluşturuldu ancak analiz servisine gönderilemedi: "+err.Error(), 500)
+		logging.From(ctx).Error("speaker enrollment failed", logging.KeyService, "identificate", "error_kind", services.KindOf(err), "error", err)
+		http.Error(w, "Kullanıcı oluşturuldu ancak analiz servisine gönderilemedi: "+err.Error(), enrollmentStatus(err))
 		return
 	}
 
@@ -156,6 +173,7 @@ func HandleGetSegments(w http.ResponseWriter, r *http.Request) {
 			Speaker:        s.Speaker,
 			TextSentiment:  s.TextSentiment,
 			VoiceSentiment: s.VoiceSentiment,
+			Channel:        s.Channel,
 		})
 	}
 
@@ -165,3 +183,33 @@ func HandleGetSegments(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(results)
 }
+
+// decodeStatus: Yüklenen dosyanın çözme hatasını HTTP koduna çevirir
+func decodeStatus(err error) int {
+	switch {
+	case errors.Is(err, audio.ErrTooLong):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, audio.ErrUnknownFormat), errors.Is(err, audio.ErrUnsupportedWAV):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, audio.ErrFFmpegMissing):
+		return http.StatusServiceUnavailable // Sunucu yapılandırması: bu biçim şu an çözülemiyor
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusUnprocessableEntity // Bozuk veya çözülemeyen ses
+	}
+}
+
+// enrollmentStatus: Servis hata türünü istemciye dönülecek HTTP koduna çevirir
+func enrollmentStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBadInput):
+		return http.StatusBadRequest // Ses kaydı servis tarafından reddedildi
+	case errors.Is(err, services.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, services.ErrModelError), errors.Is(err, services.ErrDecode):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
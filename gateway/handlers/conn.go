@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gateway/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// clientSendBuffer: Bir bağlantıya yazılmayı bekleyen en fazla mesaj. Dolarsa istemci okumuyor
+// demektir; bağlantı kapatılır ki mesajı üreten (örn. segment worker'ı) beklemesin.
+const clientSendBuffer = 256
+
+// errSlowClient: İstemci mesajları okumadığı için yazma kuyruğu doldu ve bağlantı kapatıldı
+var errSlowClient = errors.New("istemci mesajları okumuyor, bağlantı kapatıldı")
+
+// errConnClosed: Bağlantı kapandı veya kapanıyor; mesaj gönderilmedi
+var errConnClosed = errors.New("bağlantı kapandı")
+
+// liveConn: Açık bir WebSocket bağlantısı. Mesajlar kuyruğa alınır ve bağlantının kendi yazıcı
+// goroutine'i tarafından write_timeout sınırıyla yazılır; gönderen ağ yazmasını hiç beklemez.
+type liveConn struct {
+	conn         *websocket.Conn
+	shuttingDown atomic.Bool
+	// Bağlantının kendi log satırları için; oturuma (veya izleyici olarak) bağlanınca oturumun
+	// record_id taşıyan logger'ı olur
+	log atomic.Pointer[slog.Logger]
+
+	out      chan map[string]interface{}
+	quit     chan struct{} // close veya abort ile kapanır; sonrasında mesaj kabul edilmez
+	quitOnce sync.Once
+	done     chan struct{} // Yazıcı goroutine'i bitti
+}
+
+func newLiveConn(conn *websocket.Conn) *liveConn {
+	lc := &liveConn{
+		conn: conn,
+		out:  make(chan map[string]interface{}, clientSendBuffer),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	lc.log.Store(slog.Default().With("remote_addr", conn.RemoteAddr().String()))
+	go lc.writeLoop()
+	return lc
+}
+
+// setLogger: Bağlantının log satırlarını oturumun logger'ına bağlar
+func (lc *liveConn) setLogger(log *slog.Logger) {
+	lc.log.Store(log)
+}
+
+func (lc *liveConn) logger() *slog.Logger {
+	return lc.log.Load()
+}
+
+// enqueue: Mesajı yazma kuyruğuna ekler. wait=false ise beklemez; kuyruk doluysa bağlantı
+// kapatılır ve errSlowClient döner. wait=true sadece bağlantıya ait goroutine'den kullanılır.
+func (lc *liveConn) enqueue(msg map[string]interface{}, wait bool) error {
+	select {
+	case <-lc.quit:
+		return errConnClosed
+	default:
+	}
+	if wait {
+		select {
+		case lc.out <- msg:
+			return nil
+		case <-lc.quit:
+			return errConnClosed
+		}
+	}
+	select {
+	case lc.out <- msg:
+		return nil
+	default:
+		lc.logger().Warn("client send queue full, closing connection", "limit", clientSendBuffer)
+		lc.abort()
+		return errSlowClient
+	}
+}
+
+func (lc *liveConn) writeLoop() {
+	defer close(lc.done)
+	for {
+		select {
+		case msg := <-lc.out:
+			if !lc.write(msg) {
+				return
+			}
+		case <-lc.quit:
+			// Kapanış: kuyrukta kalanlar yazılır (abort edildiyse bağlantı kapalı olduğu için hemen düşer)
+			for {
+				select {
+				case msg := <-lc.out:
+					if !lc.write(msg) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (lc *liveConn) write(msg map[string]interface{}) bool {
+	lc.conn.SetWriteDeadline(time.Now().Add(config.C.WriteTimeout.Duration))
+	if err := lc.conn.WriteJSON(msg); err != nil {
+		lc.logger().Info("websocket write failed, closing connection", "error", err)
+		lc.abort()
+		return false
+	}
+	return true
+}
+
+// abort: Bağlantıyı kuyruğu beklemeden kapatır; okuma döngüsü bunu kopan bağlantı olarak görür
+func (lc *liveConn) abort() {
+	lc.quitOnce.Do(func() { close(lc.quit) })
+	lc.conn.Close()
+}
+
+// close: Kuyruktaki mesajlar yazıldıktan sonra bağlantıyı verilen kodla kapatır.
+// code 0 ise kapanış çerçevesi gönderilmez (bağlantı zaten kopmuş).
+func (lc *liveConn) close(code int, reason string) {
+	lc.quitOnce.Do(func() { close(lc.quit) })
+	<-lc.done
+	if code != 0 {
+		lc.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
+}
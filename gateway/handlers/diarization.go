@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gateway/database"
+	"gateway/models"
+)
+
+const (
+	diarizationChannel     = "channel" // Her kanal ayrı bir konuşmacı (çağrı merkezi, röportaj kayıtları)
+	maxDiarizationChannels = 8
+	maxSpeakerNameLength   = 64
+)
+
+// channelSpeaker: Kanal bazlı ayrıştırmada bir kanalın konuşmacısı. Ad verilmezse user_id'deki
+// kullanıcının adı, o da yoksa "Kanal N" kullanılır.
+//
+//	{"name": "Temsilci", "user_id": 12}
+type channelSpeaker struct {
+	Name   string `json:"name,omitempty"`
+	UserID uint   `json:"user_id,omitempty"`
+}
+
+// channelLimit: Ham PCM'de kabul edilen en fazla kanal
+func (s sessionSettings) channelLimit() int {
+	if s.Diarization == diarizationChannel {
+		return maxDiarizationChannels
+	}
+	return maxChannels
+}
+
+// validateDiarization: Ayrıştırma modu ve kanal konuşmacıları; bağlı kullanıcıların var olduğu da doğrulanır
+func (s sessionSettings) validateDiarization() error {
+	var errs []error
+	switch s.Diarization {
+	case "":
+		if len(s.ChannelSpeakers) > 0 {
+			errs = append(errs, fmt.Errorf("channel_speakers sadece diarization %q ile kullanılabilir", diarizationChannel))
+		}
+		return errors.Join(errs...)
+	case diarizationChannel:
+	default:
+		return fmt.Errorf("diarization %q desteklenmiyor (%q)", s.Diarization, diarizationChannel)
+	}
+
+	// Kanallar ayrı ayrı segmentlenir; sıkıştırılmış akışlar çözülürken mono'ya indirgenir
+	if s.Encoding != encodingPCM16 {
+		errs = append(errs, fmt.Errorf("diarization %q sadece %s ile kullanılabilir", diarizationChannel, encodingPCM16))
+	}
+	if s.Channels < 2 {
+		errs = append(errs, fmt.Errorf("diarization %q en az 2 kanal gerektirir, %d verildi", diarizationChannel, s.Channels))
+	}
+	if len(s.ChannelSpeakers) > s.Channels {
+		errs = append(errs, fmt.Errorf("channel_speakers %d kanal için %d konuşmacı içeriyor", s.Channels, len(s.ChannelSpeakers)))
+	}
+	for i, sp := range s.ChannelSpeakers {
+		if utf8.RuneCountInString(sp.Name) > maxSpeakerNameLength {
+			errs = append(errs, fmt.Errorf("channel_speakers[%d].name en fazla %d karakter olabilir", i, maxSpeakerNameLength))
+		}
+		if sp.UserID == 0 {
+			continue
+		}
+		var n int64
+		if database.DB.Model(&models.User{}).Where("id = ?", sp.UserID).Count(&n); n == 0 {
+			errs = append(errs, fmt.Errorf("channel_speakers[%d].user_id %d bulunamadı", i, sp.UserID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// channelSpeakers: Kanal başına gösterilecek konuşmacı adı (kanal bazlı ayrıştırma yoksa nil).
+// Oturum açılırken bir kez çözülür; oturum boyunca kullanıcı adı değişse de segmentler tutarlı kalır.
+func (s sessionSettings) channelSpeakers() []string {
+	if s.Diarization != diarizationChannel {
+		return nil
+	}
+	names := make([]string, s.Channels)
+	for i := range names {
+		names[i] = fmt.Sprintf("Kanal %d", i+1)
+		if i >= len(s.ChannelSpeakers) {
+			continue
+		}
+		sp := s.ChannelSpeakers[i]
+		var user models.User
+		switch {
+		case sp.Name != "":
+			names[i] = sp.Name
+		case sp.UserID != 0 && database.DB.First(&user, sp.UserID).Error == nil:
+			names[i] = fmt.Sprintf("%s %s", user.Name, user.Surname)
+		}
+	}
+	return names
+}
@@ -0,0 +1,8 @@
+package handlers
+
+// ResetLifecycle: Shutdown'dan sonra sunucunun yeniden oturum kabul etmesini sağlar (sadece testler)
+func ResetLifecycle() {
+	lifecycle.Lock()
+	lifecycle.draining = false
+	lifecycle.Unlock()
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"gateway/audio"
+	"gateway/config"
+	"gateway/services"
+)
+
+// Services: Handler'ların kullandığı dış servisler.
+// main'de services.HTTPClient ile doldurulur; testlerde sahte gerçeklemeler verilebilir.
+type Services struct {
+	Transcriber   services.Transcriber
+	VoiceAnalyzer services.VoiceAnalyzer
+	TextAnalyzer  services.TextAnalyzer
+	TopicAnalyzer services.TopicAnalyzer
+	Enroller      services.Enroller
+	Pingers       map[string]services.Pinger // /readyz'de servis adıyla kontrol edilir
+}
+
+// Handler: Dış servislere ihtiyaç duyan endpoint'leri taşır
+type Handler struct {
+	svc      Services
+	pool     *workerPool     // Canlı oturum segmentlerinin analizi
+	decoders *audio.Registry // Kayıt için yüklenen ses dosyaları
+	// Analiz için yüklenen kayıtlar: çözülen ses analiz bitene kadar bellekte kaldığı için sayıları sınırlıdır
+	recordings chan struct{}
+}
+
+func New(svc Services) *Handler {
+	return &Handler{
+		svc:        svc,
+		pool:       newWorkerPool(config.C.SegmentWorkers, config.C.GlobalQueueLimit),
+		decoders:   audio.NewDefaultRegistry(config.C.FFmpegPath),
+		recordings: make(chan struct{}, config.C.MaxConcurrentRecordings),
+	}
+}
+
+// NewHTTPServices: Tüm arayüzleri aynı HTTP istemcisiyle doldurur
+func NewHTTPServices(client *services.HTTPClient) Services {
+	return Services{
+		Transcriber:   client,
+		VoiceAnalyzer: client,
+		TextAnalyzer:  client,
+		TopicAnalyzer: client,
+		Enroller:      client,
+		Pingers:       client.Pingers(),
+	}
+}
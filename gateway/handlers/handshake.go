@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gateway/audio"
+	"gateway/config"
+)
+
+const (
+	encodingPCM16   = "pcm_s16le" // Ham, little-endian 16-bit PCM (kanallar iç içe)
+	encodingWebM    = "webm_opus" // Opus-in-WebM (tarayıcı MediaRecorder); gateway'de ffmpeg ile çözülür
+	encodingOgg     = "ogg_opus"  // Opus-in-Ogg
+	maxTitleLength  = 200
+	maxParticipants = 32
+	defaultLanguage = "tr"
+	maxChannels     = 2 // Çok kanallı PCM mono'ya indirgenir (kanal bazlı ayrıştırma hariç)
+)
+
+// pcmSampleRates: Ham PCM için kabul edilen hızlar; sunucunun sample_rate'inden farklıysa
+// gateway VAD'den önce yeniden örnekler
+var pcmSampleRates = []int{8000, 16000, 22050, 32000, 44100, 48000}
+
+// sessionSettings: İstemcinin ilk "start" mesajıyla bildirdiği oturum ayarları.
+//
+//	{"v": 1, "type": "start", "sample_rate": 48000, "channels": 2, "encoding": "pcm_s16le", "language": "tr",
+//	 "vad_aggressiveness": 3, "expected_participants": 2, "title": "Haftalık toplantı",
+//	 "silence_hangover_ms": 500, "min_segment_ms": 3000, "max_segment_ms": 30000, "pre_roll_ms": 200,
+//	 "diarization": "channel", "channel_speakers": [{"name": "Temsilci", "user_id": 12}, {"name": "Müşteri"}]}
+//
+// Alan verilmezse varsayılan kullanılır (segmentasyon alanları için sunucunun yapılandırması);
+// start göndermeden doğrudan ses yollayan eski istemciler de varsayılan ayarlarla çalışır.
+type sessionSettings struct {
+	SampleRate           int    `json:"sample_rate"`
+	Channels             int    `json:"channels"` // Sadece pcm_s16le
+	Encoding             string `json:"encoding"`
+	Language             string `json:"language"`
+	VADAggressiveness    int    `json:"vad_aggressiveness"`
+	ExpectedParticipants int    `json:"expected_participants"` // 0: bilinmiyor
+	Title                string `json:"title"`
+
+	// Segmentasyon (milisaniye)
+	SilenceHangoverMs int `json:"silence_hangover_ms"`
+	MinSegmentMs      int `json:"min_segment_ms"`
+	MaxSegmentMs      int `json:"max_segment_ms"` // Sunucunun max_segment değerini aşamaz
+	PreRollMs         int `json:"pre_roll_ms"`
+
+	// Kanal bazlı ayrıştırma: her kanal kendi VAD'iyle segmentlenir, konuşmacı kanaldan gelir
+	Diarization     string           `json:"diarization"`
+	ChannelSpeakers []channelSpeaker `json:"channel_speakers,omitempty"`
+}
+
+func defaultSettings() sessionSettings {
+	p := config.C.SegmentationPolicy
+	return sessionSettings{
+		SampleRate:        config.SampleRate,
+		Channels:          1,
+		Encoding:          encodingPCM16,
+		Language:          defaultLanguage,
+		VADAggressiveness: p.VADAggressiveness,
+		SilenceHangoverMs: int(p.SilenceHangover.Milliseconds()),
+		MinSegmentMs:      int(p.MinSegment.Milliseconds()),
+		MaxSegmentMs:      int(p.MaxSegment.Milliseconds()),
+		PreRollMs:         int(p.PreRoll.Milliseconds()),
+	}
+}
+
+// policy: Oturumun segmentasyon kuralları
+func (s sessionSettings) policy() config.SegmentationPolicy {
+	ms := func(v int) config.Duration { return config.Duration{Duration: time.Duration(v) * time.Millisecond} }
+	return config.SegmentationPolicy{
+		VADAggressiveness: s.VADAggressiveness,
+		SilenceHangover:   ms(s.SilenceHangoverMs),
+		MinSegment:        ms(s.MinSegmentMs),
+		MaxSegment:        ms(s.MaxSegmentMs),
+		PreRoll:           ms(s.PreRollMs),
+	}
+}
+
+// startMessage: Çözümlenmiş "start" mesajı
+type startMessage struct {
+	Settings sessionSettings
+	// Kopan bir oturumu devam ettirmek için önceki session_id; verilirse
+	// oturumun ilk ayarları geçerli kalır ve Settings kullanılmaz.
+	ResumeSessionID string
+	// Oturum başlarken session_started ile verilen resume_token
+	ResumeToken string
+	// Devam ettirmede istemcinin aldığı son live_analysis'in seq'i; sonrakiler tekrar gönderilir.
+	// nil: sunucu, bağlantı koptuktan sonra gönderemediği sonuçları tekrar gönderir.
+	LastSeq *int
+}
+
+// parseStart: Metin mesajı bir "start" ise ayarları varsayılanlarla birleştirip doğrular.
+// ok=false: mesaj start değil (örn. eski istemcinin "STOP"u).
+func parseStart(data []byte) (start startMessage, ok bool, err error) {
+	var msg struct {
+		Version int    `json:"v"`
+		Type    string `json:"type"`
+		settingsMessage
+		ResumeSessionID string `json:"resume_session_id"`
+		ResumeToken     string `json:"resume_token"`
+		LastSeq         *int   `json:"last_seq"`
+	}
+	if json.Unmarshal(data, &msg) != nil || msg.Type != msgStart {
+		return startMessage{}, false, nil
+	}
+	if err := checkVersion(msg.Version); err != nil {
+		return startMessage{}, true, err
+	}
+
+	settings := msg.settings()
+	err = settings.validate()
+	if msg.LastSeq != nil && *msg.LastSeq < 0 {
+		err = errors.Join(err, fmt.Errorf("last_seq negatif olamaz, %d verildi", *msg.LastSeq))
+	}
+	return startMessage{Settings: settings, ResumeSessionID: msg.ResumeSessionID, ResumeToken: msg.ResumeToken, LastSeq: msg.LastSeq}, true, err
+}
+
+// settingsMessage: start mesajının (ve yüklenen kaydın "settings" alanının) ayar alanları
+type settingsMessage struct {
+	sessionSettings
+	// 0'ı verilmedi ile ayırt etmek için işaretçi (0 geçerli olabilir veya hata olarak bildirilmeli)
+	VADAggressiveness *int `json:"vad_aggressiveness"`
+	SilenceHangoverMs *int `json:"silence_hangover_ms"`
+	MinSegmentMs      *int `json:"min_segment_ms"`
+	MaxSegmentMs      *int `json:"max_segment_ms"`
+	PreRollMs         *int `json:"pre_roll_ms"`
+}
+
+// settings: Verilen alanları varsayılanlarla birleştirir (doğrulamaz)
+func (msg settingsMessage) settings() sessionSettings {
+	settings := defaultSettings()
+	if msg.SampleRate != 0 {
+		settings.SampleRate = msg.SampleRate
+	}
+	if msg.Channels != 0 {
+		settings.Channels = msg.Channels
+	}
+	if msg.Encoding != "" {
+		settings.Encoding = msg.Encoding
+	}
+	if msg.Language != "" {
+		settings.Language = strings.ToLower(msg.Language)
+	}
+	for _, o := range []struct {
+		from *int
+		to   *int
+	}{
+		{msg.VADAggressiveness, &settings.VADAggressiveness},
+		{msg.SilenceHangoverMs, &settings.SilenceHangoverMs},
+		{msg.MinSegmentMs, &settings.MinSegmentMs},
+		{msg.MaxSegmentMs, &settings.MaxSegmentMs},
+		{msg.PreRollMs, &settings.PreRollMs},
+	} {
+		if o.from != nil {
+			*o.to = *o.from
+		}
+	}
+	settings.Diarization = msg.Diarization
+	settings.ChannelSpeakers = msg.ChannelSpeakers
+	for i := range settings.ChannelSpeakers {
+		settings.ChannelSpeakers[i].Name = strings.TrimSpace(settings.ChannelSpeakers[i].Name)
+	}
+	settings.ExpectedParticipants = msg.ExpectedParticipants
+	settings.Title = strings.TrimSpace(msg.Title)
+
+	return settings
+}
+
+// validate: Tüm hataları birlikte döner
+func (s sessionSettings) validate() error {
+	var errs []error
+	switch s.Encoding {
+	case encodingPCM16:
+		if !slices.Contains(pcmSampleRates, s.SampleRate) {
+			errs = append(errs, fmt.Errorf("sample_rate %d desteklenmiyor (%v)", s.SampleRate, pcmSampleRates))
+		}
+		if limit := s.channelLimit(); s.Channels < 1 || s.Channels > limit {
+			errs = append(errs, fmt.Errorf("channels 1-%d arasında olmalı, %d verildi", limit, s.Channels))
+		}
+	case encodingWebM, encodingOgg:
+		// Çözücü sunucunun örnekleme hızına dönüştürür; sample_rate bilgi amaçlıdır
+		if err := audio.FFmpegAvailable(config.C.FFmpegPath); err != nil {
+			errs = append(errs, fmt.Errorf("encoding %q bu sunucuda kullanılamıyor: %v", s.Encoding, err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("encoding %q desteklenmiyor (%s, %s, %s)", s.Encoding, encodingPCM16, encodingWebM, encodingOgg))
+	}
+	if !validLanguage(s.Language) {
+		errs = append(errs, fmt.Errorf("language %q geçerli bir ISO 639-1 kodu değil", s.Language))
+	}
+	if err := s.validateDiarization(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.policy().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if limit := config.C.MaxSegment; s.policy().MaxSegment.Duration > limit.Duration {
+		errs = append(errs, fmt.Errorf("max_segment_ms %d sunucu sınırını (%s) aşıyor", s.MaxSegmentMs, limit))
+	}
+	if s.ExpectedParticipants < 0 || s.ExpectedParticipants > maxParticipants {
+		errs = append(errs, fmt.Errorf("expected_participants 0-%d arasında olmalı, %d verildi", maxParticipants, s.ExpectedParticipants))
+	}
+	if utf8.RuneCountInString(s.Title) > maxTitleLength {
+		errs = append(errs, fmt.Errorf("title en fazla %d karakter olabilir", maxTitleLength))
+	}
+	return errors.Join(errs...)
+}
+
+func validLanguage(lang string) bool {
+	if len(lang) != 2 {
+		return false
+	}
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
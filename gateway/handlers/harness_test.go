@@ -0,0 +1,362 @@
+package handlers_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gateway/config"
+	"gateway/database"
+	"gateway/handlers"
+	"gateway/models"
+	"gateway/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// Uçtan uca test düzeneği: gerçek gateway rotaları httptest üzerinde, Python servisleri ise
+// aynı JSON sözleşmesini konuşan deterministik sahte sunucularla çalışır.
+
+const (
+	fakeTopic          = "Test Toplantısı"
+	fakeTextSentiment  = "Olumlu"
+	fakeVoiceSentiment = "Mutlu"
+	fakeSimilarity     = 0.87
+)
+
+// fakeML: Whisper (5000), Audio (5001) ve Text (5002) servislerinin sahteleri
+type fakeML struct {
+	whisper *httptest.Server
+	audio   *httptest.Server
+	text    *httptest.Server
+
+	mu              sync.Mutex
+	whisperFailures int                                 // Whisper'ın sıradaki kaç isteğe 503 döneceği
+	whisperCalls    int                                 // Whisper'a gelen toplam istek
+	whisperActive   int                                 // Şu an yanıtlanmakta olan Whisper isteği
+	whisperPeak     int                                 // Aynı anda yanıtlanan en fazla Whisper isteği
+	languages       []string                            // Whisper'a ?language= ile gelen diller
+	whisperDelay    func(seconds float64) time.Duration // Ses süresine göre yapay Whisper gecikmesi
+	whisperSeconds  []float64                           // Whisper'a gelen her isteğin ses süresi
+	audioStatus     int                                 // 0 değilse /analyze_audio bu kodla {"error": ...} döner
+	audioCalls      int                                 // /analyze_audio'ya gelen toplam istek
+	speakerID       string                              // /analyze_audio yanıtındaki konuşmacı
+	topicTexts      []string                            // /analyze_topic'e gelen metinler
+	wavSizes        []int                               // /analyze_audio'ya gelen WAV boyutları
+	enrolled        [][]byte                            // /identificate'e gelen WAV'lar
+}
+
+func newFakeML(t *testing.T) *fakeML {
+	t.Helper()
+	f := &fakeML{speakerID: "Unknown"}
+
+	// Whisper: ham 16-bit PCM alır, süreye bağlı tek bir segment döner
+	f.whisper = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		f.mu.Lock()
+		f.whisperCalls++
+		f.languages = append(f.languages, r.URL.Query().Get("language"))
+		fail := f.whisperFailures > 0
+		if fail {
+			f.whisperFailures--
+		}
+		delay := f.whisperDelay
+		f.whisperActive++
+		f.whisperPeak = max(f.whisperPeak, f.whisperActive)
+		f.mu.Unlock()
+		defer func() {
+			f.mu.Lock()
+			f.whisperActive--
+			f.mu.Unlock()
+		}()
+		if fail {
+			http.Error(w, "model busy", http.StatusServiceUnavailable)
+			return
+		}
+
+		pcm, _ := io.ReadAll(r.Body)
+		if len(pcm) == 0 {
+			http.Error(w, "Audio data is missing", http.StatusBadRequest)
+			return
+		}
+		dur := float64(len(pcm)) / float64(config.C.BytesPerSecond())
+		f.mu.Lock()
+		f.whisperSeconds = append(f.whisperSeconds, dur)
+		f.mu.Unlock()
+		if delay != nil {
+			time.Sleep(delay(dur))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"segments": []map[string]interface{}{{
+				"text":  fmt.Sprintf("%.1f saniyelik konuşma", roundTo(dur, 0.5)),
+				"start": 0.2,
+				"end":   dur - 0.2,
+			}},
+			"language": "tr",
+		})
+	}))
+
+	audioMux := http.NewServeMux()
+	audioMux.HandleFunc("/analyze_audio", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.audioCalls++
+		status := f.audioStatus
+		f.mu.Unlock()
+		if status != 0 {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Model not loaded"})
+			return
+		}
+		var req struct {
+			WavFile []byte `json:"wav_file"` // Go tarafı base64 olarak gönderir
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.WavFile) < 44 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Missing wav_file"})
+			return
+		}
+		if string(req.WavFile[:4]) != "RIFF" || string(req.WavFile[8:12]) != "WAVE" {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "not a wav file"})
+			return
+		}
+		f.mu.Lock()
+		f.wavSizes = append(f.wavSizes, len(req.WavFile))
+		speaker := f.speakerID
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"voice_sentiment":  fakeVoiceSentiment,
+			"speaker":          speaker,
+			"similarity_score": fakeSimilarity,
+			"status":           "success",
+		})
+	})
+	audioMux.HandleFunc("/identificate", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			WavFile []byte `json:"wav_file"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.enrolled = append(f.enrolled, req.WavFile)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	})
+	f.audio = httptest.NewServer(audioMux)
+
+	textMux := http.NewServeMux()
+	textMux.HandleFunc("/sentiment", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "text": req.Text, "sentiment": fakeTextSentiment})
+	})
+	textMux.HandleFunc("/analyze_topic", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.topicTexts = append(f.topicTexts, req.Text)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "topic": fakeTopic})
+	})
+	f.text = httptest.NewServer(textMux)
+
+	t.Cleanup(func() {
+		f.whisper.Close()
+		f.audio.Close()
+		f.text.Close()
+	})
+	return f
+}
+
+// harness: Sahte servislere bağlı, geçici SQLite kullanan gateway
+type harness struct {
+	t   *testing.T
+	ml  *fakeML
+	srv *httptest.Server
+}
+
+// newHarness: opts, Handler oluşturulmadan önce yapılandırmayı değiştirir (örn. worker havuzu boyutu)
+func newHarness(t *testing.T, opts ...func(*config.Config)) *harness {
+	t.Helper()
+	ml := newFakeML(t)
+
+	cfg := config.Default()
+	cfg.DBPath = filepath.Join(t.TempDir(), "test.sqlite")
+	cfg.WhisperServiceURL = ml.whisper.URL
+	cfg.AudioServiceURL = ml.audio.URL
+	cfg.TextServiceURL = ml.text.URL
+	cfg.ServiceTimeout = config.Duration{Duration: 5 * time.Second}
+	// Ara transkripsiyonlar Whisper çağrı sayılarını değiştirir; sadece onları test edenler açar
+	cfg.InterimInterval = config.Duration{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := config.C
+	config.C = cfg
+	database.Init()
+
+	h := handlers.New(handlers.NewHTTPServices(services.NewHTTPClient(cfg)))
+	srv := httptest.NewServer(h.Routes())
+
+	t.Cleanup(func() {
+		srv.Close()
+		database.Close()
+		config.C = prev
+	})
+	return &harness{t: t, ml: ml, srv: srv}
+}
+
+// dial: /ws bağlantısı açar
+func (h *harness) dial() *websocket.Conn {
+	h.t.Helper()
+	url := "ws" + strings.TrimPrefix(h.srv.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		h.t.Fatalf("ws dial: %v", err)
+	}
+	h.t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// streamPCM: PCM'i gerçek istemci gibi küçük parçalar halinde gönderir
+func (h *harness) streamPCM(conn *websocket.Conn, pcm []byte) {
+	h.t.Helper()
+	const chunk = 640 * 5
+	for i := 0; i < len(pcm); i += chunk {
+		end := min(i+chunk, len(pcm))
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcm[i:end]); err != nil {
+			h.t.Fatalf("ws write: %v", err)
+		}
+	}
+}
+
+// readUntilClose: Sunucu bağlantıyı kapatana kadar gelen tüm JSON mesajlarını toplar
+func (h *harness) readUntilClose(conn *websocket.Conn, timeout time.Duration) []map[string]json.RawMessage {
+	h.t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	var msgs []map[string]json.RawMessage
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+				h.t.Fatalf("server did not close the session within %s", timeout)
+			}
+			return msgs
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			h.t.Fatalf("non-JSON message %q: %v", data, err)
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// liveResults: "live_analysis" mesajlarının payload'larını döner
+func liveResults(t *testing.T, msgs []map[string]json.RawMessage) []models.LiveAnalysisResult {
+	t.Helper()
+	var out []models.LiveAnalysisResult
+	for _, m := range msgs {
+		var typ string
+		json.Unmarshal(m["type"], &typ)
+		if typ != "live_analysis" {
+			continue
+		}
+		var p models.LiveAnalysisResult
+		if err := json.Unmarshal(m["payload"], &p); err != nil {
+			t.Fatalf("bad live_analysis payload: %v", err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// str: Mesajdaki metin alanı (yoksa boş)
+func str(m map[string]json.RawMessage, key string) string {
+	var s string
+	json.Unmarshal(m[key], &s)
+	return s
+}
+
+// waitFor: Koşul sağlanana kadar kısa aralıklarla bekler
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// --- PCM Fikstürü ---
+
+// speechPCM: webrtcvad'in konuşma olarak algıladığı deterministik, formantlı harmonik sinyal
+func speechPCM(seconds float64) []byte {
+	return speechPCMAt(config.SampleRate, seconds)
+}
+
+// speechPCMAt: speechPCM'in verilen örnekleme hızındaki hali
+func speechPCMAt(rate int, seconds float64) []byte {
+	n := int(seconds * float64(rate))
+	buf := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		t := float64(i) / float64(rate)
+		f0 := 140 + 20*math.Sin(2*math.Pi*3*t)
+		var v float64
+		for k := 1; k <= 20; k++ {
+			fk := f0 * float64(k)
+			amp := 1 / float64(k)
+			amp *= 1 + 3*math.Exp(-math.Pow((fk-700)/150, 2)) + 2*math.Exp(-math.Pow((fk-1200)/200, 2))
+			v += amp * math.Sin(2*math.Pi*fk*t)
+		}
+		v *= 0.6 + 0.4*math.Sin(2*math.Pi*4*t)
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(int16(v*2500)))
+	}
+	return buf
+}
+
+func silencePCM(seconds float64) []byte {
+	return make([]byte, int(seconds*float64(config.C.BytesPerSecond())))
+}
+
+// stereoPCM: İki mono kanalı iç içe (interleaved) stereo PCM'e çevirir
+func stereoPCM(left, right []byte) []byte {
+	out := make([]byte, 0, 2*len(left))
+	for i := 0; i+1 < len(left); i += 2 {
+		out = append(out, left[i:i+2]...)
+		out = append(out, right[i:i+2]...)
+	}
+	return out
+}
+
+func concatPCM(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func roundTo(v, step float64) float64 {
+	return math.Round(v/step) * step
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"gateway/config"
+	"gateway/database"
+)
+
+// Tek bir bağımlılığın kontrol sonucu
+type dependencyStatus struct {
+	Status    string  `json:"status"` // "up" | "down"
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                      `json:"status"` // "ready" | "not_ready" | "draining"
+	Checks map[string]dependencyStatus `json:"checks"`
+}
+
+// GET /healthz
+// Liveness: Süreç ayakta ve istek karşılayabiliyor mu? Bağımlılıklara bakmaz.
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// GET /readyz
+// Readiness: SQLite ve Python servislerini kısa zaman aşımıyla paralel kontrol eder.
+// Herhangi biri erişilemezse (veya sunucu kapanıyorsa) 503 döner.
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	checks := map[string]func(ctx context.Context) error{
+		"database": database.Ping,
+	}
+	for name, p := range h.svc.Pingers {
+		checks[name] = p.Ping
+	}
+
+	resp := readinessResponse{Status: "ready", Checks: make(map[string]dependencyStatus, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), config.C.HealthTimeout.Duration)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			status := dependencyStatus{
+				Status:    "up",
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = "down"
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			resp.Checks[name] = status
+			if err != nil {
+				resp.Status = "not_ready"
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	if isDraining() {
+		resp.Status = "draining"
+	}
+
+	if resp.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
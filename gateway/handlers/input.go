@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"gateway/audio"
+	"gateway/config"
+	"gateway/metrics"
+)
+
+// decoderDrainTimeout: Giriş kapandıktan sonra çözücünün kalan sesi bitirmesi için beklenen süre
+const decoderDrainTimeout = 5 * time.Second
+
+// decoderWriteTimeout: Çözücünün bir ses çerçevesini kabul etmesi için beklenen en fazla süre; aşılırsa
+// çözücü takılmış sayılır ve bağlantıya decode_failed gönderilir (okuma döngüsü sonsuza dek beklemez)
+const decoderWriteTimeout = 10 * time.Second
+
+// audioInput: Bir bağlantının ses girişi. Ham PCM doğrudan VAD döngüsüne gider (sunucunun hızında
+// ve mono değilse önce yeniden örneklenir; kanal bazlı ayrıştırmada her kanal ayrı track'e ayrılıp
+// ayrı örneklenir); sıkıştırılmış kodlamalar bağlantı başına bir ffmpeg süreciyle çözülür (devam ettirilen bağlantı yeni bir
+// WebM/Ogg akışı başlatır). Çözülen PCM okuma döngüsünde işlendiği için oturum durumu tek
+// goroutine'de kalır.
+type audioInput struct {
+	dec     audio.StreamDecoder // nil: ham PCM
+	res     []*audio.Resampler  // Track başına; nil: o track'in PCM'i zaten sunucunun hızında ve mono
+	pending []byte              // Kanallara ayrılırken yarım kalan çerçeve
+	cancel  context.CancelFunc
+}
+
+func newAudioInput(s *liveSession) (*audioInput, error) {
+	var format string
+	switch s.Settings.Encoding {
+	case encodingWebM:
+		format = audio.FormatWebM
+	case encodingOgg:
+		format = audio.FormatOgg
+	default:
+		in := &audioInput{res: make([]*audio.Resampler, len(s.tracks)), cancel: func() {}}
+		// Kanallara ayrılan her track mono gelir; tek track ise bütün kanalları indirger
+		channels := s.Settings.Channels
+		if len(s.tracks) > 1 {
+			channels = 1
+		}
+		if st := s.Settings; st.SampleRate != config.SampleRate || channels > 1 {
+			for i := range in.res {
+				in.res[i] = audio.NewResampler(st.SampleRate, config.SampleRate, channels)
+			}
+		}
+		return in, nil
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	dec, err := audio.NewStreamDecoder(ctx, config.C.FFmpegPath, format, config.SampleRate, decoderWriteTimeout)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &audioInput{dec: dec, cancel: cancel}, nil
+}
+
+// ready: Çözülmüş PCM hazır (ham PCM için hiç sinyal vermez)
+func (in *audioInput) ready() <-chan struct{} {
+	if in.dec == nil {
+		return nil
+	}
+	return in.dec.Ready()
+}
+
+// write: İstemciden gelen ses çerçevesi
+func (h *Handler) write(s *liveSession, in *audioInput, data []byte) {
+	if in.dec == nil {
+		parts := [][]byte{data}
+		if len(s.tracks) > 1 {
+			var rest []byte
+			parts, rest = audio.SplitChannels(append(in.pending, data...), len(s.tracks))
+			in.pending = append([]byte(nil), rest...)
+		}
+		for i, pcm := range parts {
+			if in.res[i] != nil {
+				pcm = in.res[i].Process(pcm)
+			}
+			h.processAudio(s, s.tracks[i], pcm)
+		}
+		return
+	}
+	if _, err := in.dec.Write(data); err != nil {
+		// Süreç bitti veya takıldığı için durduruldu; nedeni ready sinyalinden sonra drain ile alınır
+		s.log.Debug("audio decoder write failed", "error", err)
+	}
+}
+
+// drain: O ana kadar çözülmüş PCM'i işler. done: çözücü bitti (err varsa akış bozuk)
+func (h *Handler) drain(s *liveSession, in *audioInput) (done bool, err error) {
+	pcm, done, err := in.dec.Drain()
+	if len(pcm) > 0 {
+		h.processAudio(s, s.tracks[0], pcm)
+	}
+	if err != nil {
+		metrics.StreamDecodeFailures.Inc()
+	}
+	return done, err
+}
+
+// closeInput: Bağlantının sesi bitti (stop, kopma veya kapanış). Çözücünün ve yeniden örnekleyicinin
+// elindeki ses de segmentlere girsin diye girişi kapatıp çözücünün bitmesini bekler.
+func (h *Handler) closeInput(s *liveSession, in *audioInput) {
+	defer in.cancel()
+	for i, res := range in.res {
+		if res != nil {
+			h.processAudio(s, s.tracks[i], res.Flush())
+		}
+	}
+	if in.dec == nil {
+		return
+	}
+	in.dec.Close()
+
+	timeout := time.NewTimer(decoderDrainTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-in.dec.Ready():
+			done, err := h.drain(s, in)
+			if err != nil {
+				s.log.Warn("audio stream ended with decode error", "error", err)
+			}
+			if done {
+				return
+			}
+		case <-timeout.C:
+			s.log.Warn("audio decoder did not finish, remaining audio dropped", "timeout", decoderDrainTimeout.String())
+			return
+		}
+	}
+}
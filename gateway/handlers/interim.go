@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gateway/config"
+	"gateway/logging"
+	"gateway/metrics"
+	"gateway/models"
+	"gateway/services"
+	"gateway/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// interimWindow: Ara transkripsiyona gönderilen en uzun ses; uzun konuşmalarda sadece son kısım
+// gönderilir ki her ara istek büyüyen segmentin tamamını yeniden çözmesin
+const interimWindow = 10 * time.Second
+
+// interim: Süren konuşmanın son interimWindow'luk sesini geçici transkripsiyona gönderir.
+// Sonuç, segment kesildiğinde alacağı seq ile interim_analysis olarak yayınlanır; aynı seq'in
+// live_analysis'i gelince istemci geçici metni onunla değiştirir.
+// Ara transkripsiyon en iyi çabadır: önceki hâlâ sürüyorsa veya ara kuyruk doluysa atlanır,
+// oturum kuyruğunda yer tutmaz, nihai segmentlerden sonra çalışır ve overloaded göndermez.
+func (h *Handler) interim(s *liveSession, t *track) {
+	t.interimBytes = len(t.currentSegment)
+	if !t.interimBusy.CompareAndSwap(false, true) {
+		metrics.InterimTranscripts.WithLabelValues("skipped").Inc()
+		return
+	}
+
+	seq := s.segmentSeq + 1
+	tail := t.currentSegment[max(0, len(t.currentSegment)-config.C.DurationBytes(interimWindow)):]
+	offsetSec := t.segmentOffset() + float64(len(t.currentSegment)-len(tail))/float64(config.C.BytesPerSecond())
+	pcm := make([]byte, len(tail))
+	copy(pcm, tail)
+	ctx, _ := logging.With(s.ctx, logging.KeySegment, seq)
+
+	s.wg.Add(1)
+	job := func() {
+		defer s.wg.Done()
+		defer t.interimBusy.Store(false)
+		h.transcribeInterim(ctx, s, seq, pcm, offsetSec)
+	}
+	if !h.pool.tryInterim(job) {
+		s.wg.Done()
+		t.interimBusy.Store(false)
+		metrics.InterimTranscripts.WithLabelValues("skipped").Inc()
+	}
+}
+
+// transcribeInterim: Sadece Whisper çalışır; duygu ve konuşmacı analizi nihai segmente kalır
+func (h *Handler) transcribeInterim(ctx context.Context, s *liveSession, seq int, pcm []byte, offset float64) {
+	ctx, span := tracing.Start(ctx, "segment.interim", trace.WithAttributes(
+		attribute.String(logging.KeyRecordID, s.ID),
+		attribute.Float64("segment.offset_sec", offset),
+		attribute.Int("segment.bytes", len(pcm)),
+	))
+	defer span.End()
+	log := logging.From(ctx)
+
+	resp, err := h.svc.Transcriber.Transcribe(ctx, pcm, s.Settings.Language)
+	if err != nil {
+		// Nihai segment aynı sesi tekrar gönderir; hata orada istemciye bildirilir
+		log.Debug("interim transcription failed", logging.KeyService, "whisper", "error_kind", services.KindOf(err), "error", err)
+		metrics.InterimTranscripts.WithLabelValues("error").Inc()
+		return
+	}
+	if len(resp.Segments) == 0 {
+		return
+	}
+
+	texts := make([]string, 0, len(resp.Segments))
+	for _, seg := range resp.Segments {
+		texts = append(texts, strings.TrimSpace(seg.Text))
+	}
+	result := models.InterimResult{
+		Start: offset + resp.Segments[0].Start,
+		End:   offset + resp.Segments[len(resp.Segments)-1].End,
+		Text:  strings.Join(texts, " "),
+	}
+
+	sent := s.reorder.interim(seq, func() {
+		s.publishLogged(log, msgInterim, map[string]interface{}{"seq": seq, "payload": result})
+	})
+	if !sent {
+		log.Debug("interim transcript superseded by final result")
+		metrics.InterimTranscripts.WithLabelValues("stale").Inc()
+		return
+	}
+	metrics.InterimTranscripts.WithLabelValues("sent").Inc()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// lifecycle: Açık oturumları ve arka plan işlerini (segment analizi, konu analizi) takip eder.
+// Kapanışta yeni oturum kabul edilmez, mevcutlar boşaltılır ve hepsi bitene kadar beklenir.
+var lifecycle = struct {
+	sync.Mutex
+	draining bool
+	sessions map[*liveConn]struct{}
+	wg       sync.WaitGroup
+}{sessions: make(map[*liveConn]struct{})}
+
+func isDraining() bool {
+	lifecycle.Lock()
+	defer lifecycle.Unlock()
+	return lifecycle.draining
+}
+
+// beginSession: Oturumu kaydeder; sunucu kapanıyorsa false döner
+func beginSession(lc *liveConn) bool {
+	lifecycle.Lock()
+	defer lifecycle.Unlock()
+	if lifecycle.draining {
+		return false
+	}
+	lifecycle.sessions[lc] = struct{}{}
+	lifecycle.wg.Add(1)
+	return true
+}
+
+func endSession(lc *liveConn) {
+	lifecycle.Lock()
+	delete(lifecycle.sessions, lc)
+	lifecycle.Unlock()
+	lifecycle.wg.Done()
+}
+
+// goBackground: Oturum bittikten sonra da süren işleri (konu analizi) kapanışta beklenecek şekilde başlatır.
+// Sadece kayıtlı bir oturumun içinden çağrılmalı (WaitGroup sayacı sıfıra düşmüş olmamalı).
+func goBackground(fn func()) {
+	lifecycle.wg.Add(1)
+	go func() {
+		defer lifecycle.wg.Done()
+		fn()
+	}()
+}
+
+// startBackground: Bağlantısı olmayan bir işi (yüklenen kaydın analizi) kapanışta beklenecek şekilde
+// başlatır; sunucu kapanıyorsa başlatmaz ve false döner
+func startBackground(fn func()) bool {
+	lifecycle.Lock()
+	defer lifecycle.Unlock()
+	if lifecycle.draining {
+		return false
+	}
+	lifecycle.wg.Add(1)
+	go func() {
+		defer lifecycle.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// Shutdown: Yeni oturumları reddeder, bağlı istemcilere kapanışı bildirir ve
+// tüm oturumlar ile bekleyen işler bitene (veya ctx dolana) kadar bekler.
+func Shutdown(ctx context.Context) error {
+	lifecycle.Lock()
+	lifecycle.draining = true
+	open := make([]*liveConn, 0, len(lifecycle.sessions))
+	for lc := range lifecycle.sessions {
+		open = append(open, lc)
+	}
+	lifecycle.Unlock()
+
+	// Bağlantısı kopmuş oturumlar artık devam ettirilemez; hemen sonlandırılır
+	expireSuspended()
+
+	slog.Info("draining live sessions", "open_sessions", len(open))
+	for _, lc := range open {
+		lc.shuttingDown.Store(true)
+
+		lc.sendLogged(slog.Default(), msgServerShutdown, map[string]interface{}{
+			"message": "Sunucu yeniden başlatılıyor, mevcut kayıt tamamlanıyor.",
+		})
+
+		// Okuma döngüsünü hemen sonlandır; döngü son segmenti STOP gibi işler
+		lc.conn.SetReadDeadline(time.Now())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		lifecycle.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("all live sessions and background jobs finished")
+		return nil
+	case <-ctx.Done():
+		// Süre doldu: kalan bağlantıları zorla kapat
+		lifecycle.Lock()
+		for lc := range lifecycle.sessions {
+			lc.conn.Close()
+		}
+		lifecycle.Unlock()
+		return ctx.Err()
+	}
+}
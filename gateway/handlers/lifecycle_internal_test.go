@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gateway/config"
+)
+
+// Kapanış başladıktan sonra askıya alınan oturum expireSuspended'ın taramasını kaçırmış olabilir;
+// suspend bunu kendi kilidi altında görüp reddetmeli, bağlantıyı yine de bırakmalı
+func TestSuspendRefusedWhileDraining(t *testing.T) {
+	prev := config.C
+	config.C = config.Default()
+	config.C.ResumeGrace = config.Duration{Duration: time.Minute}
+	t.Cleanup(func() { config.C = prev })
+
+	s := newLiveSession(context.Background(), slog.Default(), newSessionID(), defaultSettings())
+	t.Cleanup(s.unregister)
+	s.conn.Store(&liveConn{})
+
+	lifecycle.Lock()
+	lifecycle.draining = true
+	lifecycle.Unlock()
+	t.Cleanup(ResetLifecycle)
+
+	if s.suspend(func() { t.Error("expire called for a session that was never suspended") }) {
+		t.Fatal("suspend succeeded while draining; the session would wait out resume_grace")
+	}
+	if s.conn.Load() != nil {
+		t.Error("connection still attached after a refused suspend")
+	}
+	if takeTimer(s) {
+		t.Error("grace timer installed while draining")
+	}
+}
@@ -0,0 +1,1589 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gateway/audio"
+	"gateway/config"
+	"gateway/database"
+	"gateway/handlers"
+	"gateway/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// İki konuşma (3.5 s ve 4 s) arasında VAD'in segmenti kesmesi için yeterli sessizlik var
+func twoUtteranceFixture() []byte {
+	return concatPCM(
+		speechPCM(3.5), silencePCM(1),
+		speechPCM(4), silencePCM(1),
+	)
+}
+
+func TestLiveSessionEndToEnd(t *testing.T) {
+	h := newHarness(t)
+
+	user := models.User{Name: "Ada", Surname: "Lovelace", VoicePath: "remote_stored"}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.ml.speakerID = fmt.Sprint(user.ID)
+
+	conn := h.dial()
+	h.streamPCM(conn, twoUtteranceFixture())
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("STOP")); err != nil {
+		t.Fatal(err)
+	}
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+
+	// 1. WebSocket: her konuşma için bir live_analysis
+	if len(results) != 2 {
+		t.Fatalf("got %d live_analysis messages, want 2: %+v", len(results), results)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Start < results[j].Start })
+	for _, r := range results {
+		if r.Speaker != "Ada Lovelace" {
+			t.Errorf("speaker = %q, want resolved user name", r.Speaker)
+		}
+		if r.TextSentiment != fakeTextSentiment || r.VoiceSentiment != fakeVoiceSentiment {
+			t.Errorf("sentiments = %q/%q", r.TextSentiment, r.VoiceSentiment)
+		}
+		if r.SimilarityScore != fakeSimilarity {
+			t.Errorf("similarity = %v", r.SimilarityScore)
+		}
+	}
+	// Segment zaman damgaları oturum başına göre: ikinci konuşma ~4.5 s'de başlar, segment ise
+	// 200 ms pre-roll ile 4.3 s'den gönderilir (sahte Whisper konuşmayı segment içinde 0.2 s'de başlatır)
+	if got := results[0].Start; math.Abs(got-0.2) > 0.15 {
+		t.Errorf("first segment start = %.2f, want ~0.2", got)
+	}
+	if got := results[1].Start; math.Abs(got-4.5) > 0.15 {
+		t.Errorf("second segment start = %.2f, want ~4.5", got)
+	}
+
+	// 2. Veritabanı: aynı segmentler kayda bağlı olarak saklanmış olmalı
+	var record models.Record
+	if err := database.DB.First(&record).Error; err != nil {
+		t.Fatalf("record not created: %v", err)
+	}
+	var segments []models.Segment
+	database.DB.Where("record_id = ?", record.ID).Order("start_offset asc").Find(&segments)
+	if len(segments) != len(results) {
+		t.Fatalf("persisted %d segments, want %d", len(segments), len(results))
+	}
+	for i, s := range segments {
+		if s.Text != results[i].Text || s.StartOffset != results[i].Start || s.Speaker != results[i].Speaker {
+			t.Errorf("segment %d = %+v, does not match live result %+v", i, s, results[i])
+		}
+	}
+
+	// 3. Konu analizi oturumdan sonra arka planda çalışır
+	waitFor(t, 5*time.Second, "record topic", func() bool {
+		var r models.Record
+		database.DB.First(&r, "id = ?", record.ID)
+		return r.Topic == fakeTopic
+	})
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	if len(h.ml.topicTexts) != 1 || h.ml.topicTexts[0] != segments[0].Text+" "+segments[1].Text {
+		t.Errorf("topic analysis received %q", h.ml.topicTexts)
+	}
+}
+
+func TestLiveSessionSilenceOnly(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	h.streamPCM(conn, silencePCM(3))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	if results := liveResults(t, h.readUntilClose(conn, 5*time.Second)); len(results) != 0 {
+		t.Fatalf("got %d results for silence, want none", len(results))
+	}
+	var count int64
+	database.DB.Model(&models.Segment{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("persisted %d segments for silence", count)
+	}
+}
+
+func TestReadyzWithFakeServices(t *testing.T) {
+	h := newHarness(t)
+
+	resp, err := http.Get(h.srv.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/readyz = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestLiveSessionRetriesTransientWhisperFailure(t *testing.T) {
+	h := newHarness(t)
+	h.ml.whisperFailures = 1
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want the segment to survive one 503", len(results))
+	}
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	if h.ml.whisperCalls != 2 {
+		t.Fatalf("whisper called %d times, want 2 (1 failure + 1 retry)", h.ml.whisperCalls)
+	}
+}
+
+func TestLiveSessionAudioModelErrorFallsBack(t *testing.T) {
+	h := newHarness(t)
+	h.ml.audioStatus = http.StatusInternalServerError
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	// 500 gövdesi başarılı yanıt gibi çözülüp boş alanlar dönmemeli
+	if r := results[0]; r.Speaker != "Unknown" || r.VoiceSentiment != "Bilinmiyor" {
+		t.Errorf("speaker/voice = %q/%q, want fallback values", r.Speaker, r.VoiceSentiment)
+	}
+	if results[0].TextSentiment != fakeTextSentiment {
+		t.Errorf("text sentiment = %q, audio failure must not affect it", results[0].TextSentiment)
+	}
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	if h.ml.audioCalls != 1 {
+		t.Fatalf("audio called %d times, model errors must not be retried", h.ml.audioCalls)
+	}
+}
+
+func TestConcurrentSessionsAreIsolated(t *testing.T) {
+	h := newHarness(t)
+
+	// Aynı saniyede açılan iki oturum ayrı kayıtlara yazmalı
+	a, b := h.dial(), h.dial()
+	h.streamPCM(a, concatPCM(speechPCM(3.5), silencePCM(1)))
+	h.streamPCM(b, silencePCM(1))
+
+	var active []struct {
+		ID              string `json:"id"`
+		ClientAddr      string `json:"client_addr"`
+		BytesReceived   int64  `json:"bytes_received"`
+		SegmentsEmitted int64  `json:"segments_emitted"`
+	}
+	waitFor(t, 5*time.Second, "both sessions in registry", func() bool {
+		resp, err := http.Get(h.srv.URL + "/api/sessions/active")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		active = nil
+		json.NewDecoder(resp.Body).Decode(&active)
+		return len(active) == 2 && active[0].BytesReceived+active[1].BytesReceived == int64(len(speechPCM(3.5))+2*len(silencePCM(1)))
+	})
+	if active[0].ID == active[1].ID {
+		t.Fatalf("sessions share ID %q", active[0].ID)
+	}
+	for _, s := range active {
+		if s.ClientAddr == "" {
+			t.Errorf("session %s has no client address", s.ID)
+		}
+	}
+
+	a.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	b.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	if n := len(liveResults(t, h.readUntilClose(a, 10*time.Second))); n != 1 {
+		t.Fatalf("session A got %d results, want 1", n)
+	}
+	if n := len(liveResults(t, h.readUntilClose(b, 5*time.Second))); n != 0 {
+		t.Fatalf("session B got %d results from another session", n)
+	}
+
+	var records []models.Record
+	database.DB.Find(&records)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	var segs []models.Segment
+	database.DB.Find(&segs)
+	if len(segs) != 1 || (segs[0].RecordID != active[0].ID && segs[0].RecordID != active[1].ID) {
+		t.Fatalf("segments = %+v", segs)
+	}
+	waitFor(t, 5*time.Second, "registry to empty", func() bool {
+		resp, err := http.Get(h.srv.URL + "/api/sessions/active")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		active = nil
+		json.NewDecoder(resp.Body).Decode(&active)
+		return len(active) == 0
+	})
+}
+
+func TestLiveSessionStartHandshake(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	conn.WriteJSON(map[string]interface{}{
+		"type":                  "start",
+		"sample_rate":           16000,
+		"encoding":              "pcm_s16le",
+		"language":              "en",
+		"vad_aggressiveness":    2,
+		"expected_participants": 3,
+		"title":                 "  Haftalık toplantı ",
+	})
+
+	// Onay, ses gönderilmeden önce gelmeli
+	var ack struct {
+		Type      string `json:"type"`
+		SessionID string `json:"session_id"`
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.Type != "session_started" || ack.SessionID == "" {
+		t.Fatalf("ack = %+v, want session_started with ID", ack)
+	}
+
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	if n := len(liveResults(t, h.readUntilClose(conn, 10*time.Second))); n != 1 {
+		t.Fatalf("got %d results, want 1", n)
+	}
+
+	var record models.Record
+	if err := database.DB.First(&record, "id = ?", ack.SessionID).Error; err != nil {
+		t.Fatalf("record %s not created: %v", ack.SessionID, err)
+	}
+	if record.Title != "Haftalık toplantı" || record.Language != "en" || record.VADAggressiveness != 2 ||
+		record.ExpectedParticipants != 3 || record.SampleRate != 16000 || record.Encoding != "pcm_s16le" {
+		t.Errorf("record settings = %+v", record)
+	}
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	if len(h.ml.languages) != 1 || h.ml.languages[0] != "en" {
+		t.Errorf("whisper languages = %q, want [en]", h.ml.languages)
+	}
+}
+
+func TestLiveSessionInvalidStartRejected(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	conn.WriteJSON(map[string]interface{}{"type": "start", "sample_rate": 44000, "channels": 3, "vad_aggressiveness": 7, "pre_roll_ms": 5000})
+
+	msgs := h.readUntilClose(conn, 5*time.Second)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want a single error", len(msgs))
+	}
+	var code, message string
+	json.Unmarshal(msgs[0]["code"], &code)
+	json.Unmarshal(msgs[0]["message"], &message)
+	if code != "invalid_start" || !strings.Contains(message, "sample_rate") || !strings.Contains(message, "channels") ||
+		!strings.Contains(message, "vad_aggressiveness") || !strings.Contains(message, "pre_roll") {
+		t.Errorf("error = %s: %s", code, message)
+	}
+	var count int64
+	database.DB.Model(&models.Record{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("rejected session created %d records", count)
+	}
+}
+
+func TestLiveSessionControlProtocol(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "start"})
+	h.streamPCM(conn, speechPCM(3.5))
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "pause", "id": "p1"})
+	h.streamPCM(conn, speechPCM(2)) // Duraklatılmışken analiz edilmemeli
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "resume", "id": "r1"})
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "ping", "id": "x"})
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "mark", "id": "m1", "label": "karar"})
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "dance"})
+	h.streamPCM(conn, silencePCM(1))
+	conn.WriteJSON(map[string]interface{}{"v": 1, "type": "stop", "id": "s1"})
+
+	msgs := h.readUntilClose(conn, 10*time.Second)
+
+	acks := map[string]map[string]json.RawMessage{}
+	var states, errorCodes []string
+	var complete map[string]json.RawMessage
+	var lastDepth int
+	for _, m := range msgs {
+		var v int
+		if json.Unmarshal(m["v"], &v); v != 1 {
+			t.Errorf("message without protocol version: %v", m)
+		}
+		switch str(m, "type") {
+		case "ack":
+			acks[str(m, "id")] = m
+		case "status":
+			states = append(states, str(m, "state"))
+		case "error":
+			errorCodes = append(errorCodes, str(m, "code"))
+		case "queue":
+			json.Unmarshal(m["depth"], &lastDepth)
+		case "session_complete":
+			complete = m
+		}
+	}
+
+	for _, id := range []string{"p1", "r1", "x", "m1", "s1"} {
+		if acks[id] == nil {
+			t.Errorf("no ack for %s", id)
+		}
+	}
+	var markOffset float64
+	json.Unmarshal(acks["m1"]["offset_sec"], &markOffset)
+	if math.Abs(markOffset-5.5) > 0.05 || str(acks["m1"], "label") != "karar" {
+		t.Errorf("mark ack = offset %.2f label %q, want ~5.5 (paused audio counts on the timeline)", markOffset, str(acks["m1"], "label"))
+	}
+	if fmt.Sprint(states) != "[paused recording processing]" {
+		t.Errorf("status states = %v", states)
+	}
+	if fmt.Sprint(errorCodes) != "[unknown_type]" {
+		t.Errorf("error codes = %v", errorCodes)
+	}
+	if lastDepth != 0 {
+		t.Errorf("final queue depth = %d", lastDepth)
+	}
+	if n := len(liveResults(t, msgs)); n != 1 {
+		t.Errorf("got %d live results, want only the pre-pause utterance", n)
+	}
+
+	// session_complete en son gelir ve konuyu taşır
+	if complete == nil || str(msgs[len(msgs)-1], "type") != "session_complete" {
+		t.Fatalf("last message is not session_complete: %v", msgs[len(msgs)-1])
+	}
+	if str(complete, "topic") != fakeTopic {
+		t.Errorf("session_complete topic = %q", str(complete, "topic"))
+	}
+}
+
+func TestLiveSessionReportsServiceErrors(t *testing.T) {
+	h := newHarness(t)
+	h.ml.audioStatus = http.StatusInternalServerError
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	var found bool
+	for _, m := range h.readUntilClose(conn, 10*time.Second) {
+		var typ, service, kind string
+		json.Unmarshal(m["type"], &typ)
+		json.Unmarshal(m["service"], &service)
+		json.Unmarshal(m["kind"], &kind)
+		if typ == "service_error" && service == "audio" && kind == "model_error" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("no service_error message for the failing audio service")
+	}
+}
+
+// sessionAck: session_started onayı
+type sessionAck struct {
+	Type        string  `json:"type"`
+	Code        string  `json:"code"`
+	SessionID   string  `json:"session_id"`
+	Resumed     bool    `json:"resumed"`
+	OffsetSec   float64 `json:"offset_sec"`
+	WatchToken  string  `json:"watch_token"`
+	ResumeToken string  `json:"resume_token"`
+}
+
+// startSessionAck: start mesajını gönderir ve session_started onayını döner
+func startSessionAck(t *testing.T, conn *websocket.Conn, start map[string]interface{}) sessionAck {
+	t.Helper()
+	start["type"] = "start"
+	if err := conn.WriteJSON(start); err != nil {
+		t.Fatal(err)
+	}
+	var ack sessionAck
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.Type != "session_started" {
+		t.Fatalf("got %s (%s), want session_started", ack.Type, ack.Code)
+	}
+	return ack
+}
+
+// startSession: startSessionAck'in sık kullanılan alanları
+func startSession(t *testing.T, conn *websocket.Conn, start map[string]interface{}) (id string, resumed bool, offset float64) {
+	t.Helper()
+	ack := startSessionAck(t, conn, start)
+	return ack.SessionID, ack.Resumed, ack.OffsetSec
+}
+
+func TestLiveSessionResumeAfterDisconnect(t *testing.T) {
+	h := newHarness(t)
+
+	first := h.dial()
+	ack := startSessionAck(t, first, map[string]interface{}{"title": "Kesintili"})
+	id := ack.SessionID
+	h.streamPCM(first, concatPCM(speechPCM(3.5), silencePCM(1)))
+	waitFor(t, 5*time.Second, "first segment", func() bool {
+		var n int64
+		database.DB.Model(&models.Segment{}).Where("record_id = ?", id).Count(&n)
+		return n == 1
+	})
+	first.Close() // STOP olmadan kopma
+
+	waitFor(t, 5*time.Second, "session to be suspended", func() bool {
+		for _, s := range activeSessionsOf(t, h) {
+			if s.ID == id && !s.Connected {
+				return true
+			}
+		}
+		return false
+	})
+	// Listelenen kimlik tek başına yetmez: anahtarsız veya yanlış anahtarlı deneme reddedilir, oturum askıda kalır
+	for _, token := range []string{"", "0123456789abcdef0123456789abcdef"} {
+		intruder := h.dial()
+		intruder.WriteJSON(map[string]interface{}{"type": "start", "resume_session_id": id, "resume_token": token})
+		if code := errorCode(h.readUntilClose(intruder, 5*time.Second)); code != "resume_failed" {
+			t.Fatalf("resume with token %q: got %q, want resume_failed", token, code)
+		}
+	}
+
+	second := h.dial()
+	resumedID, resumed, offset := startSession(t, second, map[string]interface{}{"resume_session_id": id, "resume_token": ack.ResumeToken})
+	if resumedID != id || !resumed {
+		t.Fatalf("resumed session %q (resumed=%v), want %q", resumedID, resumed, id)
+	}
+	if math.Abs(offset-4.5) > 0.05 {
+		t.Errorf("resume offset = %.2f, want 4.5", offset)
+	}
+
+	h.streamPCM(second, concatPCM(speechPCM(3.5), silencePCM(1)))
+	second.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	results := liveResults(t, h.readUntilClose(second, 10*time.Second))
+	// Devam eden akışta segment 200 ms pre-roll ile 4.3 s'den başlar
+	if len(results) != 1 || math.Abs(results[0].Start-4.5) > 0.15 {
+		t.Fatalf("results after resume = %+v, want one segment starting ~4.5", results)
+	}
+
+	var records int64
+	database.DB.Model(&models.Record{}).Count(&records)
+	var segments []models.Segment
+	database.DB.Where("record_id = ?", id).Order("start_offset asc").Find(&segments)
+	if records != 1 || len(segments) != 2 || segments[1].StartOffset <= segments[0].EndOffset {
+		t.Fatalf("records=%d segments=%+v, want one record with monotonic segments", records, segments)
+	}
+}
+
+func TestLiveSessionResumeAfterGraceFails(t *testing.T) {
+	h := newHarness(t)
+	config.C.ResumeGrace = config.Duration{Duration: 100 * time.Millisecond}
+
+	first := h.dial()
+	ack := startSessionAck(t, first, map[string]interface{}{})
+	id := ack.SessionID
+	h.streamPCM(first, concatPCM(speechPCM(3.5), silencePCM(1)))
+	first.Close()
+
+	// Süre dolunca oturum sonlanır: konu analizi çalışır ve ID artık kabul edilmez
+	waitFor(t, 5*time.Second, "expired session topic", func() bool {
+		var r models.Record
+		database.DB.First(&r, "id = ?", id)
+		return r.Topic == fakeTopic
+	})
+
+	second := h.dial()
+	second.WriteJSON(map[string]interface{}{"type": "start", "resume_session_id": id, "resume_token": ack.ResumeToken})
+	msgs := h.readUntilClose(second, 5*time.Second)
+	if code := errorCode(msgs); code != "resume_failed" {
+		t.Fatalf("got %v, want resume_failed error", msgs)
+	}
+}
+
+// errorCode: Bağlantıda tek mesaj olarak gelen hatanın kodu
+func errorCode(msgs []map[string]json.RawMessage) string {
+	var code string
+	if len(msgs) == 1 {
+		json.Unmarshal(msgs[0]["code"], &code)
+	}
+	return code
+}
+
+// Kapanış ile bağlantı kopması yarışırsa oturum askıda unutulmaz: Shutdown grace süresini beklemez
+func TestShutdownConcurrentWithDisconnect(t *testing.T) {
+	h := newHarness(t)
+	config.C.ResumeGrace = config.Duration{Duration: time.Minute}
+	t.Cleanup(handlers.ResetLifecycle)
+
+	for i := range 20 {
+		conn := h.dial()
+		startSession(t, conn, map[string]interface{}{})
+		h.streamPCM(conn, silencePCM(0.5))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Duration(i%5) * time.Millisecond)
+			conn.Close() // STOP olmadan kopma
+		}()
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := handlers.Shutdown(ctx)
+		cancel()
+		wg.Wait()
+		if err != nil {
+			t.Fatalf("iteration %d: Shutdown = %v, want all sessions finished before the deadline", i, err)
+		}
+		if n := len(activeSessionsOf(t, h)); n != 0 {
+			t.Fatalf("iteration %d: %d sessions still registered after Shutdown", i, n)
+		}
+		handlers.ResetLifecycle()
+	}
+}
+
+type activeSessionView struct {
+	ID         string `json:"id"`
+	Connected  bool   `json:"connected"`
+	QueueDepth int64  `json:"queue_depth"`
+}
+
+// waitSuspended: Oturum askıya alınana ve analizi süren segmentleri bitene kadar bekler
+func waitSuspended(t *testing.T, h *harness, id string) {
+	t.Helper()
+	waitFor(t, 5*time.Second, "session to be suspended and idle", func() bool {
+		for _, s := range activeSessionsOf(t, h) {
+			if s.ID == id {
+				return !s.Connected && s.QueueDepth == 0
+			}
+		}
+		return false
+	})
+}
+
+// readUntilType: typ türünde bir mesaj gelene kadar okur (o mesaj dahil)
+func readUntilType(t *testing.T, conn *websocket.Conn, typ string) []map[string]json.RawMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msgs []map[string]json.RawMessage
+	for {
+		var m map[string]json.RawMessage
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("waiting for %s: %v", typ, err)
+		}
+		msgs = append(msgs, m)
+		if str(m, "type") == typ {
+			return msgs
+		}
+	}
+}
+
+// replayedResults: Devam ettirmede tekrar gönderilen live_analysis'lerin seq'leri ve session_started'daki sayı
+func replayedResults(t *testing.T, msgs []map[string]json.RawMessage) (seqs []int, announced int) {
+	t.Helper()
+	for _, m := range msgs {
+		switch str(m, "type") {
+		case "session_started":
+			json.Unmarshal(m["replayed"], &announced)
+		case "live_analysis":
+			var r struct {
+				Seq      int  `json:"seq"`
+				Replayed bool `json:"replayed"`
+			}
+			raw, _ := json.Marshal(m)
+			json.Unmarshal(raw, &r)
+			if !r.Replayed {
+				t.Fatalf("live_analysis seq %d after resume is not marked replayed", r.Seq)
+			}
+			seqs = append(seqs, r.Seq)
+		}
+	}
+	return seqs, announced
+}
+
+func TestLiveSessionResumeReplaysMissedResults(t *testing.T) {
+	h := newHarness(t)
+
+	first := h.dial()
+	ack := startSessionAck(t, first, map[string]interface{}{})
+	id := ack.SessionID
+	h.streamPCM(first, concatPCM(speechPCM(3.5), silencePCM(1)))
+	readUntilType(t, first, "live_analysis") // seq 1 teslim edildi
+
+	// seq 2'nin sonucu istemci yokken biter
+	h.ml.mu.Lock()
+	h.ml.whisperDelay = func(float64) time.Duration { return 300 * time.Millisecond }
+	h.ml.mu.Unlock()
+	h.streamPCM(first, concatPCM(speechPCM(3.5), silencePCM(1)))
+	first.Close()
+	waitSuspended(t, h, id)
+
+	// last_seq verilmezse sunucu sadece gönderemediğini tekrar gönderir
+	second := h.dial()
+	second.WriteJSON(map[string]interface{}{"type": "start", "resume_session_id": id, "resume_token": ack.ResumeToken})
+	seqs, announced := replayedResults(t, readUntilType(t, second, "live_analysis"))
+	if announced != 1 || len(seqs) != 1 || seqs[0] != 2 {
+		t.Fatalf("replayed seqs %v (announced %d), want [2]", seqs, announced)
+	}
+	second.Close()
+	waitSuspended(t, h, id)
+
+	// İstemci aldığı son seq'i bildirirse ondan sonrakiler gönderilir
+	third := h.dial()
+	third.WriteJSON(map[string]interface{}{"type": "start", "resume_session_id": id, "resume_token": ack.ResumeToken, "last_seq": 0})
+	third.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	seqs, announced = replayedResults(t, h.readUntilClose(third, 10*time.Second))
+	if announced != 2 || len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Fatalf("replayed seqs %v (announced %d), want [1 2]", seqs, announced)
+	}
+}
+
+func TestLegacySessionNotSuspendedOnDisconnect(t *testing.T) {
+	h := newHarness(t) // resume_grace 30s
+
+	// start göndermeyen istemci session_id almaz; oturumu devam ettiremez, hemen sonlanmalı
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	waitFor(t, 5*time.Second, "legacy session registration", func() bool { return len(activeSessionsOf(t, h)) == 1 })
+	conn.Close()
+
+	waitFor(t, 5*time.Second, "legacy session to finish", func() bool {
+		var r models.Record
+		database.DB.First(&r)
+		return r.Topic == fakeTopic && len(activeSessionsOf(t, h)) == 0
+	})
+}
+
+func activeSessionsOf(t *testing.T, h *harness) []activeSessionView {
+	t.Helper()
+	resp, err := http.Get(h.srv.URL + "/api/sessions/active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out []activeSessionView
+	json.NewDecoder(resp.Body).Decode(&out)
+	return out
+}
+
+func TestSpectatorReceivesHistoryAndLiveEvents(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	ack := startSessionAck(t, conn, map[string]interface{}{"title": "İzlenen"})
+	id := ack.SessionID
+	h.streamPCM(conn, concatPCM(speechPCM(3.5), silencePCM(1)))
+	waitFor(t, 5*time.Second, "first segment", func() bool {
+		var n int64
+		database.DB.Model(&models.Segment{}).Where("record_id = ?", id).Count(&n)
+		return n == 1
+	})
+
+	// Anahtarsız veya yanlış anahtarla izlenemez
+	for _, token := range []string{"", "0123456789abcdef0123456789abcdef"} {
+		if code := h.watchStatus(id, token); code != http.StatusForbidden {
+			t.Errorf("watch with token %q: status %d, want 403", token, code)
+		}
+	}
+
+	// Geç katılan izleyici önce mevcut segmenti alır
+	watcher, _, err := websocket.DefaultDialer.Dial(h.watchURL(id, ack.WatchToken), nil)
+	if err != nil {
+		t.Fatalf("watch dial: %v", err)
+	}
+	defer watcher.Close()
+	waitFor(t, 5*time.Second, "spectator registration", func() bool {
+		return gaugeValue(t, h, "gateway_ws_spectators") == 1
+	})
+
+	h.streamPCM(conn, concatPCM(speechPCM(4), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	h.readUntilClose(conn, 10*time.Second)
+
+	msgs := h.readUntilClose(watcher, 10*time.Second)
+	var types []string
+	for _, m := range msgs {
+		var typ string
+		json.Unmarshal(m["type"], &typ)
+		types = append(types, typ)
+	}
+	if len(types) == 0 || types[0] != "watch_started" || types[len(types)-1] != "session_complete" {
+		t.Fatalf("spectator message types = %v", types)
+	}
+	results := liveResults(t, msgs)
+	if len(results) != 2 || results[0].Start > results[1].Start {
+		t.Fatalf("spectator got %d results, want history + live segment in order", len(results))
+	}
+	for _, want := range []string{"queue", "status"} {
+		if !strings.Contains(strings.Join(types, " "), want) {
+			t.Errorf("spectator did not receive %s events: %v", want, types)
+		}
+	}
+}
+
+func TestWatchUnknownSession(t *testing.T) {
+	h := newHarness(t)
+
+	if code := h.watchStatus("sess_missing", "token"); code != http.StatusNotFound {
+		t.Fatalf("watch unknown session: status %d, want 404", code)
+	}
+}
+
+// watchURL: Oturumu izleme adresi
+func (h *harness) watchURL(id, token string) string {
+	return "ws" + strings.TrimPrefix(h.srv.URL, "http") + "/ws/sessions/" + id + "/watch?token=" + token
+}
+
+// watchStatus: İzleme bağlantısı reddedildiyse HTTP durum kodu (açılırsa 101)
+func (h *harness) watchStatus(id, token string) int {
+	h.t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(h.watchURL(id, token), nil)
+	if err == nil {
+		conn.Close()
+		return http.StatusSwitchingProtocols
+	}
+	if resp == nil {
+		h.t.Fatalf("watch dial: %v", err)
+	}
+	return resp.StatusCode
+}
+
+// gaugeValue: /metrics çıktısından etiketsiz bir gauge değerini okur
+func gaugeValue(t *testing.T, h *harness, name string) float64 {
+	t.Helper()
+	resp, err := http.Get(h.srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, line := range strings.Split(string(body), "\n") {
+		if v, ok := strings.CutPrefix(line, name+" "); ok {
+			var f float64
+			fmt.Sscan(v, &f)
+			return f
+		}
+	}
+	return 0
+}
+
+// slowFirstUtterance: İlk (uzun) konuşmanın Whisper çağrısı ikinciden yavaş biter
+func slowFirstUtterance(h *harness, delay time.Duration) {
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	h.ml.whisperDelay = func(seconds float64) time.Duration {
+		if seconds > 4.5 {
+			return delay
+		}
+		return 0
+	}
+}
+
+type orderedResult struct {
+	Seq     int                       `json:"seq"`
+	Late    bool                      `json:"late"`
+	Payload models.LiveAnalysisResult `json:"payload"`
+}
+
+func orderedResults(msgs []map[string]json.RawMessage) []orderedResult {
+	var out []orderedResult
+	for _, m := range msgs {
+		var typ string
+		json.Unmarshal(m["type"], &typ)
+		if typ != "live_analysis" {
+			continue
+		}
+		raw, _ := json.Marshal(m)
+		var r orderedResult
+		json.Unmarshal(raw, &r)
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestLiveResultsDeliveredInOrder(t *testing.T) {
+	h := newHarness(t)
+	slowFirstUtterance(h, 500*time.Millisecond)
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(5), silencePCM(1), speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	results := orderedResults(h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Seq != 1 || results[1].Seq != 2 || results[0].Payload.Start > results[1].Payload.Start {
+		t.Fatalf("results out of order: %+v", results)
+	}
+	if results[0].Late || results[1].Late {
+		t.Errorf("no result should be late: %+v", results)
+	}
+}
+
+func TestStuckSegmentSkippedAfterReorderTimeout(t *testing.T) {
+	h := newHarness(t)
+	config.C.ReorderTimeout = config.Duration{Duration: 100 * time.Millisecond}
+	slowFirstUtterance(h, time.Second)
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(5), silencePCM(1), speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	// İkinci segment birinciyi beklemeden gelir; birinci sonradan "late" olarak teslim edilir
+	results := orderedResults(h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 || results[0].Seq != 2 || results[1].Seq != 1 || !results[1].Late || results[0].Late {
+		t.Fatalf("results = %+v, want seq 2 then late seq 1", results)
+	}
+}
+
+func TestReorderTimeoutIgnoresPoolQueueWait(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.SegmentWorkers = 2
+		c.ReorderTimeout = config.Duration{Duration: 500 * time.Millisecond}
+	})
+	h.ml.mu.Lock()
+	h.ml.whisperDelay = func(seconds float64) time.Duration {
+		switch {
+		case seconds > 7: // Diğer oturumun worker'ları meşgul eden uzun segmentleri
+			return 900 * time.Millisecond
+		case seconds > 4.5:
+			return 300 * time.Millisecond
+		}
+		return 0
+	}
+	h.ml.mu.Unlock()
+
+	busy := h.dial()
+	h.streamPCM(busy, concatPCM(speechPCM(8), silencePCM(1), speechPCM(8), silencePCM(1)))
+	waitFor(t, 5*time.Second, "both workers to be busy", func() bool {
+		h.ml.mu.Lock()
+		defer h.ml.mu.Unlock()
+		return h.ml.whisperActive == 2
+	})
+
+	// Segmentler timeout'tan uzun süre worker bekler ama işlenmeleri timeout'tan kısa sürer
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(5), silencePCM(1), speechPCM(3.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	results := orderedResults(h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 || results[0].Seq != 1 || results[1].Seq != 2 || results[0].Late || results[1].Late {
+		t.Fatalf("results = %+v, want seq 1 then 2, none late", results)
+	}
+	busy.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	h.readUntilClose(busy, 10*time.Second)
+}
+
+func TestSegmentQueueBackpressure(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.SegmentWorkers = 1
+		c.SessionQueueLimit = 1
+	})
+	h.ml.mu.Lock()
+	h.ml.whisperDelay = func(float64) time.Duration { return 300 * time.Millisecond }
+	h.ml.mu.Unlock()
+
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(
+		speechPCM(3.5), silencePCM(1),
+		speechPCM(3.5), silencePCM(1),
+		speechPCM(3.5), silencePCM(1),
+	))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	msgs := h.readUntilClose(conn, 10*time.Second)
+
+	// Kuyruk dolu olduğunda ses atılmaz: okuma döngüsü bekler, istemci overloaded alır
+	overloaded := 0
+	for _, m := range msgs {
+		var typ, scope string
+		json.Unmarshal(m["type"], &typ)
+		json.Unmarshal(m["scope"], &scope)
+		if typ == "overloaded" {
+			overloaded++
+			if scope != "session" {
+				t.Errorf("overloaded scope = %q, want session", scope)
+			}
+		}
+	}
+	if overloaded == 0 {
+		t.Error("no overloaded message while the session queue was full")
+	}
+
+	results := orderedResults(msgs)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, r := range results {
+		if r.Seq != i+1 || r.Late {
+			t.Errorf("result %d = seq %d late %v, want seq %d in order", i, r.Seq, r.Late, i+1)
+		}
+	}
+
+	h.ml.mu.Lock()
+	peak := h.ml.whisperPeak
+	h.ml.mu.Unlock()
+	if peak != 1 {
+		t.Errorf("peak concurrent Whisper requests = %d, want 1 with a single worker", peak)
+	}
+	if v := gaugeValue(t, h, "gateway_segment_queue_depth"); v != 0 {
+		t.Errorf("gateway_segment_queue_depth = %v after session, want 0", v)
+	}
+}
+
+func TestInterimTranscriptsPrecedeFinalResult(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.InterimInterval = config.Duration{Duration: 2 * time.Second}
+	})
+
+	// Gerçek zamanlı akış gibi saniyelik parçalar; önceki ara istek bitmeden yenisi atlanır
+	conn := h.dial()
+	pcm := concatPCM(speechPCM(7), silencePCM(1))
+	second := config.C.BytesPerSecond()
+	for i := 0; i < len(pcm); i += second {
+		h.streamPCM(conn, pcm[i:min(i+second, len(pcm))])
+		time.Sleep(50 * time.Millisecond)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	msgs := h.readUntilClose(conn, 10*time.Second)
+
+	// Ara sonuçlar aynı seq'i taşır, metin büyür ve hepsi nihai sonuçtan önce gelir
+	var interims []models.InterimResult
+	final := false
+	for _, m := range msgs {
+		var typ string
+		var seq int
+		json.Unmarshal(m["type"], &typ)
+		json.Unmarshal(m["seq"], &seq)
+		switch typ {
+		case "interim_analysis":
+			if final {
+				t.Fatal("interim_analysis arrived after the final live_analysis")
+			}
+			if seq != 1 {
+				t.Errorf("interim seq = %d, want 1", seq)
+			}
+			var p models.InterimResult
+			json.Unmarshal(m["payload"], &p)
+			interims = append(interims, p)
+		case "live_analysis":
+			final = true
+		}
+	}
+	if !final {
+		t.Fatal("no final live_analysis")
+	}
+	if len(interims) < 2 {
+		t.Fatalf("got %d interim transcripts for a 7 s utterance every 2 s, want at least 2", len(interims))
+	}
+	for i := 1; i < len(interims); i++ {
+		if interims[i].End <= interims[i-1].End {
+			t.Errorf("interim %d does not extend the previous one: %+v", i, interims)
+		}
+	}
+
+	// Ara sonuçlar veritabanına yazılmaz
+	var count int64
+	database.DB.Model(&models.Segment{}).Count(&count)
+	if count != 1 {
+		t.Errorf("stored %d segments, want only the final one", count)
+	}
+}
+
+// Uzun konuşmada ara istekler büyüyen segmentin tamamını değil son interimWindow'u gönderir
+func TestInterimTranscriptsUseBoundedWindow(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.InterimInterval = config.Duration{Duration: 2 * time.Second}
+	})
+
+	conn := h.dial()
+	pcm := concatPCM(speechPCM(16), silencePCM(1))
+	second := config.C.BytesPerSecond()
+	for i := 0; i < len(pcm); i += second {
+		h.streamPCM(conn, pcm[i:min(i+second, len(pcm))])
+		time.Sleep(50 * time.Millisecond)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	var interims []models.InterimResult
+	for _, m := range h.readUntilClose(conn, 10*time.Second) {
+		if str(m, "type") == "interim_analysis" {
+			var p models.InterimResult
+			json.Unmarshal(m["payload"], &p)
+			interims = append(interims, p)
+		}
+	}
+	if len(interims) == 0 {
+		t.Fatal("no interim transcripts")
+	}
+	// Pencere kaydığında zaman damgaları da kayar: son ara sonuç konuşmanın başından başlamaz
+	if last := interims[len(interims)-1]; last.Start < 4 {
+		t.Errorf("last interim starts at %.2f, want the window's start (>= 4 s)", last.Start)
+	}
+
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	const window = 10.0 // interimWindow
+	long := 0
+	for _, sec := range h.ml.whisperSeconds {
+		if sec > window {
+			long++
+		}
+	}
+	if long != 1 {
+		t.Errorf("Whisper requests longer than %.0f s: %d (%v), want only the final segment", window, long, h.ml.whisperSeconds)
+	}
+}
+
+func TestLongSpeechCutAtQuietestFrame(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.MaxSegment = config.Duration{Duration: 10 * time.Second}
+	})
+
+	// VAD'in segmenti kesmesine yetmeyen 0.2 s'lik duraklama, maksimum süreden hemen önce
+	conn := h.dial()
+	h.streamPCM(conn, concatPCM(speechPCM(8.6), silencePCM(0.2), speechPCM(6), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 (force cut + remainder)", len(results))
+	}
+	// Sahte Whisper segmenti ses başından 0.2 s sonra başlatıp sonundan 0.2 s önce bitirir
+	if firstEnd := results[0].End + 0.2; firstEnd < 8.6 || firstEnd > 8.85 {
+		t.Errorf("first segment ends at %.2f s, want the cut inside the pause (8.6-8.8 s)", firstEnd)
+	}
+	if secondStart := results[1].Start - 0.2; secondStart < 8.6 || secondStart > 8.85 {
+		t.Errorf("second segment starts at %.2f s, want it to continue from the cut", secondStart)
+	}
+	if results[1].End+0.2 < 14.7 {
+		t.Errorf("second segment ends at %.2f s, remainder after the cut was lost", results[1].End+0.2)
+	}
+}
+
+func TestSessionSegmentationPolicyOverride(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	startSession(t, conn, map[string]interface{}{
+		"silence_hangover_ms": 300,
+		"min_segment_ms":      1000,
+		"pre_roll_ms":         500,
+	})
+	h.streamPCM(conn, concatPCM(speechPCM(1.5), silencePCM(1.2), speechPCM(1.5), silencePCM(1)))
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 (1.5 s utterances pass the 1 s minimum)", len(results))
+	}
+	// İkinci konuşma 2.7 s'de başlar; segment 500 ms pre-roll ile 2.2 s'den gönderilir
+	if got := results[1].Start; math.Abs(got-2.4) > 0.05 {
+		t.Errorf("second segment start = %.2f, want ~2.4 (offset includes the pre-roll)", got)
+	}
+
+	// Sahte Whisper segmenti sesin başından 0.2 s sonra başlatıp sonundan 0.2 s önce bitirir:
+	// gönderilen sesin süresi = End - Start + 0.4
+	// Sessizlik 300 ms sonra (VAD'in kendi gecikmesiyle ~2 s'de) keser; varsayılan 500 ms daha uzun tutardı
+	if got := results[0].End - results[0].Start + 0.4; got > 2.1 {
+		t.Errorf("first segment is %.2f s, want the 300 ms hangover to end it by ~2 s", got)
+	}
+	if got := results[1].End - results[1].Start + 0.4; got < 2.0 {
+		t.Errorf("second segment is %.2f s, want it to carry the 0.5 s pre-roll", got)
+	}
+}
+
+// fakeFFmpeg: Verilen kabuk betiğini ffmpeg yerine kullanılacak çalıştırılabilir dosya olarak yazar.
+// Test akışı zaten PCM olduğundan "exec cat" gerçek Opus olmadan çözücü hattını (süreç, borular,
+// kapanışta boşaltma) sınar.
+func fakeFFmpeg(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompressedStreamDecodedInGateway(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, "exec cat")
+	h := newHarness(t, func(c *config.Config) { c.FFmpegPath = ffmpeg })
+
+	conn := h.dial()
+	startSession(t, conn, map[string]interface{}{"encoding": "webm_opus", "sample_rate": 48000})
+	h.streamPCM(conn, twoUtteranceFixture())
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "stop"}`))
+
+	// Stop'tan önce çözücüde kalan ses de segmentlere girer
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if math.Abs(results[1].Start-4.5) > 0.15 {
+		t.Errorf("second segment start = %.2f, want ~4.5 (decoded timeline)", results[1].Start)
+	}
+}
+
+func TestCompressedStreamDecodeFailureEndsSession(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, `head -c 64000; echo "Invalid data found when processing input" >&2; exit 1`)
+	h := newHarness(t, func(c *config.Config) { c.FFmpegPath = ffmpeg })
+
+	conn := h.dial()
+	startSession(t, conn, map[string]interface{}{"encoding": "ogg_opus"})
+	go func() {
+		// Çözücü bozulduktan sonra sunucu okumayı bırakır; yazma hatası beklenen durumdur
+		const chunk = 640 * 5
+		pcm := twoUtteranceFixture()
+		for i := 0; i < len(pcm); i += chunk {
+			if conn.WriteMessage(websocket.BinaryMessage, pcm[i:min(i+chunk, len(pcm))]) != nil {
+				return
+			}
+		}
+	}()
+
+	var decodeErr string
+	complete := false
+	for _, m := range h.readUntilClose(conn, 10*time.Second) {
+		switch str(m, "type") {
+		case "error":
+			if str(m, "code") == "decode_failed" {
+				decodeErr = str(m, "message")
+			}
+		case "session_complete":
+			complete = true
+		}
+	}
+	if !strings.Contains(decodeErr, "Invalid data found") {
+		t.Errorf("decode_failed message = %q, want ffmpeg's stderr", decodeErr)
+	}
+	if !complete {
+		t.Error("session was not completed after the decode failure")
+	}
+}
+
+func TestCompressedStreamRejectedWithoutFFmpeg(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) { c.FFmpegPath = filepath.Join(t.TempDir(), "missing-ffmpeg") })
+
+	conn := h.dial()
+	conn.WriteJSON(map[string]interface{}{"type": "start", "encoding": "webm_opus"})
+	msgs := h.readUntilClose(conn, 5*time.Second)
+	if len(msgs) != 1 || str(msgs[0], "code") != "invalid_start" || !strings.Contains(str(msgs[0], "message"), "ffmpeg") {
+		t.Fatalf("got %v, want invalid_start mentioning ffmpeg", msgs)
+	}
+}
+
+func TestStereoInputResampledBeforeVAD(t *testing.T) {
+	for _, rate := range []int{8000, 44100, 48000} {
+		t.Run(fmt.Sprint(rate), func(t *testing.T) {
+			h := newHarness(t)
+
+			conn := h.dial()
+			startSession(t, conn, map[string]interface{}{"sample_rate": rate, "channels": 2})
+			silence := func(s float64) []byte { return make([]byte, 2*int(s*float64(rate))) }
+			// Konuşma sadece sol kanalda: indirgeme sağ kanalın sessizliğiyle ortalar
+			left := concatPCM(speechPCMAt(rate, 3.5), silence(1), speechPCMAt(rate, 4), silence(1))
+			h.streamPCM(conn, stereoPCM(left, make([]byte, len(left))))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "stop"}`))
+
+			results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+			if len(results) != 2 {
+				t.Fatalf("got %d results, want 2", len(results))
+			}
+			// Zaman çizelgesi sunucunun hızında: ikinci konuşma yine ~4.5 s
+			if math.Abs(results[1].Start-4.5) > 0.15 {
+				t.Errorf("second segment start = %.2f, want ~4.5", results[1].Start)
+			}
+
+			var record models.Record
+			database.DB.First(&record)
+			if record.SampleRate != rate || record.Channels != 2 {
+				t.Errorf("record = %d Hz × %d, want %d Hz × 2", record.SampleRate, record.Channels, rate)
+			}
+		})
+	}
+}
+
+func TestChannelDiarizationMergesChannels(t *testing.T) {
+	h := newHarness(t)
+	user := models.User{Name: "Ada", Surname: "Lovelace", VoicePath: "remote_stored"}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Konuşmacı kanaldan gelir; ses izi eşleşmesi hiç sorulmamalı
+	h.ml.speakerID = "999"
+
+	conn := h.dial()
+	startSession(t, conn, map[string]interface{}{
+		"sample_rate": 16000, "channels": 2, "diarization": "channel",
+		"channel_speakers": []map[string]interface{}{{"user_id": user.ID}, {"name": "Müşteri"}},
+	})
+	// Konuşmalar örtüşür: 1. kanal 0-3.5 s, 2. kanal 2-6 s
+	agent := concatPCM(speechPCM(3.5), silencePCM(4.5))
+	customer := concatPCM(silencePCM(2), speechPCM(4), silencePCM(2))
+	h.streamPCM(conn, stereoPCM(agent, customer))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "stop"}`))
+
+	results := liveResults(t, h.readUntilClose(conn, 10*time.Second))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want one per channel: %+v", len(results), results)
+	}
+
+	var segments []models.Segment
+	database.DB.Order("start_offset asc").Find(&segments)
+	if len(segments) != 2 {
+		t.Fatalf("persisted %d segments, want 2", len(segments))
+	}
+	want := []struct {
+		channel int
+		speaker string
+		start   float64
+	}{{1, "Ada Lovelace", 0.2}, {2, "Müşteri", 2.0}}
+	for i, w := range want {
+		s := segments[i]
+		if s.Channel != w.channel || s.Speaker != w.speaker || math.Abs(s.StartOffset-w.start) > 0.15 {
+			t.Errorf("segment %d = channel %d %q at %.2f, want channel %d %q at ~%.1f",
+				i, s.Channel, s.Speaker, s.StartOffset, w.channel, w.speaker, w.start)
+		}
+		if s.SimilarityScore != 0 {
+			t.Errorf("segment %d similarity = %v, want 0 (voice print not used)", i, s.SimilarityScore)
+		}
+	}
+	h.ml.mu.Lock()
+	audioCalls := h.ml.audioCalls
+	h.ml.mu.Unlock()
+	if audioCalls != 0 {
+		t.Errorf("audio service called %d times for channel-diarized segments, want 0", audioCalls)
+	}
+	// Örtüşen konuşmalar kesilmeden kendi kanallarında kalır
+	if segments[0].EndOffset <= segments[1].StartOffset {
+		t.Errorf("segments do not overlap: %+v", segments)
+	}
+}
+
+func TestChannelDiarizationInvalidStart(t *testing.T) {
+	h := newHarness(t)
+
+	conn := h.dial()
+	conn.WriteJSON(map[string]interface{}{
+		"type": "start", "diarization": "channel", "channels": 1, "encoding": "webm_opus",
+		"channel_speakers": []map[string]interface{}{{"user_id": 999}},
+	})
+
+	msgs := h.readUntilClose(conn, 5*time.Second)
+	if len(msgs) != 1 || str(msgs[0], "code") != "invalid_start" {
+		t.Fatalf("got %v, want a single invalid_start error", msgs)
+	}
+	message := str(msgs[0], "message")
+	for _, want := range []string{"pcm_s16le", "en az 2 kanal", "user_id 999"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("error %q does not mention %q", message, want)
+		}
+	}
+}
+
+// uploadRecord: /api/records/upload'a dosyayı ve ayarları çok parçalı form olarak yükler
+func (h *harness) uploadRecord(file []byte, settings string) (int, map[string]json.RawMessage) {
+	h.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("settings", settings)
+	fw, _ := mw.CreateFormFile("file", "call.wav")
+	fw.Write(file)
+	mw.Close()
+
+	resp, err := http.Post(h.srv.URL+"/api/records/upload", mw.FormDataContentType(), &body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var msg map[string]json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&msg)
+	return resp.StatusCode, msg
+}
+
+// Yüklenen stereo kayıtta da her kanal kendi VAD'iyle segmentlenir ve konuşmacı kanaldan gelir
+func TestChannelDiarizationUpload(t *testing.T) {
+	h := newHarness(t)
+
+	const rate = 8000
+	silence := func(s float64) []byte { return make([]byte, 2*int(s*float64(rate))) }
+	agent := concatPCM(speechPCMAt(rate, 3.5), silence(4.5))
+	customer := concatPCM(silence(2), speechPCMAt(rate, 4), silence(2))
+	upload, err := audio.EncodeWAV(audio.Format{SampleFormat: audio.PCM16, SampleRate: rate, Channels: 2}, stereoPCM(agent, customer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, resp := h.uploadRecord(upload, `{"diarization": "channel", "channels": 2,
+		"channel_speakers": [{"name": "Temsilci"}, {"name": "Müşteri"}]}`)
+	if code != http.StatusAccepted {
+		t.Fatalf("status = %d: %v", code, resp)
+	}
+	id := str(resp, "session_id")
+	waitFor(t, 10*time.Second, "upload analysis to finish", func() bool {
+		var r models.Record
+		database.DB.First(&r, "id = ?", id)
+		return r.Topic == fakeTopic
+	})
+
+	var record models.Record
+	database.DB.First(&record, "id = ?", id)
+	if record.Channels != 2 || record.Diarization != "channel" {
+		t.Errorf("record = %d channels, diarization %q; want 2, channel", record.Channels, record.Diarization)
+	}
+	var segments []models.Segment
+	database.DB.Where("record_id = ?", id).Order("start_offset asc").Find(&segments)
+	if len(segments) != 2 {
+		t.Fatalf("persisted %d segments, want one per channel: %+v", len(segments), segments)
+	}
+	want := []struct {
+		channel int
+		speaker string
+		start   float64
+	}{{1, "Temsilci", 0.2}, {2, "Müşteri", 2.0}}
+	for i, w := range want {
+		s := segments[i]
+		if s.Channel != w.channel || s.Speaker != w.speaker || math.Abs(s.StartOffset-w.start) > 0.15 {
+			t.Errorf("segment %d = channel %d %q at %.2f, want channel %d %q at ~%.1f",
+				i, s.Channel, s.Speaker, s.StartOffset, w.channel, w.speaker, w.start)
+		}
+	}
+}
+
+func TestUploadRejectsChannelMismatch(t *testing.T) {
+	h := newHarness(t)
+
+	mono, err := audio.EncodeWAV(audio.Format{SampleFormat: audio.PCM16, SampleRate: 16000, Channels: 1}, speechPCM(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := h.uploadRecord(mono, `{"diarization": "channel", "channels": 2}`)
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+	var count int64
+	database.DB.Model(&models.Record{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d records created for a rejected upload", count)
+	}
+}
+
+// Çözülen kayıtlar analiz bitene kadar bellekte kaldığı için aynı anda işlenen kayıt sayısı sınırlıdır
+func TestUploadConcurrencyLimit(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) { c.MaxConcurrentRecordings = 1 })
+	h.ml.mu.Lock()
+	h.ml.whisperDelay = func(float64) time.Duration { return 500 * time.Millisecond }
+	h.ml.mu.Unlock()
+
+	upload, err := audio.EncodeWAV(audio.Format{SampleFormat: audio.PCM16, SampleRate: 16000, Channels: 1},
+		concatPCM(speechPCM(3.5), silencePCM(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, resp := h.uploadRecord(upload, "")
+	if code != http.StatusAccepted {
+		t.Fatalf("first upload: status = %d: %v", code, resp)
+	}
+	if code, _ := h.uploadRecord(upload, ""); code != http.StatusTooManyRequests {
+		t.Errorf("upload while another is analysed: status = %d, want 429", code)
+	}
+
+	analysed := func(resp map[string]json.RawMessage) func() bool {
+		return func() bool {
+			var r models.Record
+			database.DB.First(&r, "id = ?", str(resp, "session_id"))
+			return r.Topic == fakeTopic
+		}
+	}
+	waitFor(t, 10*time.Second, "first upload to finish", analysed(resp))
+	// Konu yazıldıktan kısa süre sonra analiz biter ve yer bırakılır
+	waitFor(t, 5*time.Second, "slot to be released", func() bool {
+		code, resp = h.uploadRecord(upload, "")
+		return code == http.StatusAccepted
+	})
+	// Test bitmeden önce bu analiz de bitmeli (yapılandırma geri yüklenirken çalışmasın)
+	waitFor(t, 10*time.Second, "second upload to finish", analysed(resp))
+}
+
+// enroll: /api/record_user'a çok parçalı form olarak dosya yükler
+func (h *harness) enroll(file []byte) (int, string) {
+	h.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "Grace")
+	mw.WriteField("surname", "Hopper")
+	fw, _ := mw.CreateFormFile("voice_record_file", "blob")
+	fw.Write(file)
+	mw.Close()
+
+	resp, err := http.Post(h.srv.URL+"/api/record_user", mw.FormDataContentType(), &body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(msg)
+}
+
+// WAV yüklemesi ffmpeg olmadan çözülür; analiz servisine sunucunun hızında mono WAV gider
+func TestEnrollmentDecodesWAVNatively(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) { c.FFmpegPath = "/nonexistent/ffmpeg" })
+
+	// 44.1 kHz stereo float32, 2 s
+	stereo := stereoPCM(speechPCMAt(44100, 2), speechPCMAt(44100, 2))
+	var f32 []byte
+	for i := 0; i+1 < len(stereo); i += 2 {
+		v := float32(int16(binary.LittleEndian.Uint16(stereo[i:]))) / 32768
+		f32 = binary.LittleEndian.AppendUint32(f32, math.Float32bits(v))
+	}
+	upload, err := audio.EncodeWAV(audio.Format{SampleFormat: audio.Float32, SampleRate: 44100, Channels: 2}, f32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, msg := h.enroll(upload); code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", code, msg)
+	}
+	h.ml.mu.Lock()
+	defer h.ml.mu.Unlock()
+	if len(h.ml.enrolled) != 1 {
+		t.Fatalf("identificate called %d times, want 1", len(h.ml.enrolled))
+	}
+	r, err := audio.NewWAVReader(bytes.NewReader(h.ml.enrolled[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (audio.Format{SampleFormat: audio.PCM16, SampleRate: 16000, Channels: 1}); r.Format() != want || r.Duration() != 2*time.Second {
+		t.Errorf("enrolled %+v, %v; want %+v, 2s", r.Format(), r.Duration(), want)
+	}
+}
+
+func TestEnrollmentRejectsUndecodableUploads(t *testing.T) {
+	webm := []byte("\x1a\x45\xdf\xa3" + strings.Repeat("\x00", 1000))
+	for _, tc := range []struct {
+		name   string
+		ffmpeg string // Sahte ffmpeg betiği; boşsa ffmpeg kurulu değil
+		opt    func(*config.Config)
+		upload []byte
+		status int
+		body   string
+	}{
+		{"unknown format", "", nil, []byte("merhaba dünya"), http.StatusUnsupportedMediaType, "tanınmayan ses biçimi"},
+		{"ffmpeg missing", "", nil, webm, http.StatusServiceUnavailable, "ffmpeg bulunamadı"},
+		{"ffmpeg error", `cat >/dev/null; echo "EBML header parsing failed" >&2; exit 1`, nil, webm, http.StatusUnprocessableEntity, "EBML header parsing failed"},
+		{"decode timeout", "exec sleep 5", func(c *config.Config) { c.DecodeTimeout = config.Duration{Duration: 200 * time.Millisecond} },
+			webm, http.StatusGatewayTimeout, "deadline exceeded"},
+		{"decoded audio too long", "cat >/dev/null; head -c 4000000 /dev/zero", nil, webm, http.StatusRequestEntityTooLarge, "ses çok uzun"},
+		{"upload too large", "", func(c *config.Config) { c.MaxUploadBytes = 4096 }, make([]byte, 8192), http.StatusRequestEntityTooLarge, "Dosya çok büyük"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ffmpeg := "/nonexistent/ffmpeg"
+			if tc.ffmpeg != "" {
+				ffmpeg = fakeFFmpeg(t, tc.ffmpeg)
+			}
+			h := newHarness(t, func(c *config.Config) {
+				c.FFmpegPath = ffmpeg
+				if tc.opt != nil {
+					tc.opt(c)
+				}
+			})
+
+			start := time.Now()
+			code, msg := h.enroll(tc.upload)
+			if code != tc.status || !strings.Contains(msg, tc.body) {
+				t.Errorf("got %d %q, want %d containing %q", code, msg, tc.status, tc.body)
+			}
+			if time.Since(start) > 3*time.Second {
+				t.Errorf("request took %v, decoder was not stopped", time.Since(start))
+			}
+			// Çözülemeyen ses için kullanıcı oluşturulmaz
+			var count int64
+			database.DB.Model(&models.User{}).Count(&count)
+			if count != 0 {
+				t.Errorf("created %d users", count)
+			}
+		})
+	}
+}
+
+// dialSlowReader: Alım tamponu küçük bir /ws bağlantısı; okumayan istemcinin sunucu yazmalarını
+// hızla tıkaması için
+func (h *harness) dialSlowReader() *websocket.Conn {
+	h.t.Helper()
+	d := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) {
+		c, err := net.Dial(network, addr)
+		if err == nil {
+			c.(*net.TCPConn).SetReadBuffer(4096)
+		}
+		return c, err
+	}}
+	conn, _, err := d.Dial("ws"+strings.TrimPrefix(h.srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		h.t.Fatalf("ws dial: %v", err)
+	}
+	h.t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestStalledClientDoesNotBlockWorkers(t *testing.T) {
+	h := newHarness(t, func(c *config.Config) {
+		c.SegmentWorkers = 1
+		c.WriteTimeout = config.Duration{Duration: 200 * time.Millisecond}
+	})
+
+	// Okumayan istemci: etiketi geri dönen büyük mark ack'leri soket tamponlarını ve yazma kuyruğunu doldurur
+	stalled := h.dialSlowReader()
+	id, _, _ := startSession(t, stalled, map[string]interface{}{})
+	h.streamPCM(stalled, concatPCM(speechPCM(3.5), silencePCM(1)))
+	mark, _ := json.Marshal(map[string]string{"type": "mark", "label": strings.Repeat("x", 16<<10)})
+	for i := 0; i < 1000; i++ {
+		if stalled.WriteMessage(websocket.TextMessage, mark) != nil {
+			break // Sunucu bağlantıyı kapattı
+		}
+	}
+	waitFor(t, 5*time.Second, "stalled client to be disconnected", func() bool {
+		for _, s := range activeSessionsOf(t, h) {
+			if s.ID == id && !s.Connected {
+				return true
+			}
+		}
+		return false
+	})
+
+	// Tek worker'ı paylaşan diğer oturum sonuçlarını almaya devam eder
+	conn := h.dial()
+	h.streamPCM(conn, twoUtteranceFixture())
+	conn.WriteMessage(websocket.TextMessage, []byte("STOP"))
+	if results := liveResults(t, h.readUntilClose(conn, 10*time.Second)); len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+}
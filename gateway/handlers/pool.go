@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"time"
+
+	"gateway/metrics"
+)
+
+// workerPool: Tüm oturumların segment analizini sınırlı sayıda worker ile yürütür.
+// CPU üzerinde çalışan Whisper'a aynı anda en fazla segment_workers istek gider;
+// worker bekleyen işler global_queue_limit kapasiteli kuyrukta tutulur.
+// Ara transkripsiyonlar ayrı ve küçük bir kuyrukta bekler; worker'lar önce segment kuyruğuna bakar,
+// böylece ara istekler nihai bir segmentin önüne geçemez.
+type workerPool struct {
+	jobs     chan poolJob
+	interims chan poolJob
+}
+
+type poolJob struct {
+	run      func()
+	queuedAt time.Time
+}
+
+func newWorkerPool(workers, queueLimit int) *workerPool {
+	p := &workerPool{
+		jobs:     make(chan poolJob, queueLimit),
+		interims: make(chan poolJob, workers),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *workerPool) work() {
+	for {
+		// Bekleyen segment varsa ara transkripsiyona bakılmaz
+		select {
+		case job := <-p.jobs:
+			p.runSegment(job)
+			continue
+		default:
+		}
+		select {
+		case job := <-p.jobs:
+			p.runSegment(job)
+		case job := <-p.interims:
+			job.run()
+		}
+	}
+}
+
+func (p *workerPool) runSegment(job poolJob) {
+	metrics.SegmentQueueDepth.Dec()
+	metrics.SegmentQueueWaitSeconds.Observe(time.Since(job.queuedAt).Seconds())
+	job.run()
+}
+
+// trySubmit: İşi kuyruğa ekler; kuyruk doluysa beklemeden false döner
+func (p *workerPool) trySubmit(run func()) bool {
+	metrics.SegmentQueueDepth.Inc()
+	select {
+	case p.jobs <- poolJob{run: run, queuedAt: time.Now()}:
+		return true
+	default:
+		metrics.SegmentQueueDepth.Dec()
+		return false
+	}
+}
+
+// submit: Kuyrukta yer açılana kadar bekler
+func (p *workerPool) submit(run func()) {
+	metrics.SegmentQueueDepth.Inc()
+	p.jobs <- poolJob{run: run, queuedAt: time.Now()}
+}
+
+// tryInterim: Ara transkripsiyonu düşük öncelikli kuyruğa ekler; kuyruk doluysa beklemeden false döner
+func (p *workerPool) tryInterim(run func()) bool {
+	select {
+	case p.interims <- poolJob{run: run, queuedAt: time.Now()}:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// protocolVersion: /ws JSON kontrol protokolünün sürümü. Sunucunun her mesajı "v" alanında taşır;
+// istemci "v" gönderirse aynı sürüm olmalıdır (gönderilmezse güncel sürüm varsayılır).
+const protocolVersion = 1
+
+// İstemci mesaj türleri
+const (
+	msgStart  = "start"
+	msgPause  = "pause"
+	msgResume = "resume"
+	msgStop   = "stop"
+	msgPing   = "ping"
+	msgMark   = "mark"
+)
+
+// Sunucu mesaj türleri
+const (
+	msgSessionStarted  = "session_started"
+	msgWatchStarted    = "watch_started" // İzleyiciye: oturum bilgisi, ardından geçmiş segmentler
+	msgAck             = "ack"
+	msgStatus          = "status"
+	msgQueue           = "queue"
+	msgOverloaded      = "overloaded" // Kuyruk dolu: ses kaybolmaz ama sonuçlar gecikir
+	msgLiveAnalysis    = "live_analysis"
+	msgInterim         = "interim_analysis" // Süren konuşmanın geçici metni
+	msgServiceError    = "service_error"
+	msgSessionComplete = "session_complete"
+	msgServerShutdown  = "server_shutdown"
+	msgError           = "error"
+)
+
+// Oturum durumları (status mesajının "state" alanı)
+const (
+	stateRecording  = "recording"
+	statePaused     = "paused"
+	stateProcessing = "processing" // Ses bitti, segmentler ve konu analizi sürüyor
+)
+
+// clientMessage: Oturum sırasında istemciden gelen kontrol mesajı.
+//
+//	{"v": 1, "type": "mark", "id": "m-3", "label": "Karar alındı"}
+//
+// "id" verilirse ack'te aynen geri döner.
+type clientMessage struct {
+	Version int    `json:"v"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Label   string `json:"label"` // Sadece mark
+}
+
+// parseClientMessage: Eski istemcilerin düz "STOP" metni de stop olarak kabul edilir
+func parseClientMessage(data []byte) (clientMessage, error) {
+	if string(data) == "STOP" {
+		return clientMessage{Version: protocolVersion, Type: msgStop}, nil
+	}
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return clientMessage{}, fmt.Errorf("geçersiz JSON: %w", err)
+	}
+	if err := checkVersion(msg.Version); err != nil {
+		return clientMessage{}, err
+	}
+	if msg.Type == "" {
+		return clientMessage{}, errors.New(`"type" alanı gerekli`)
+	}
+	return msg, nil
+}
+
+func checkVersion(v int) error {
+	if v != 0 && v != protocolVersion {
+		return fmt.Errorf("protokol sürümü %d desteklenmiyor (desteklenen %d)", v, protocolVersion)
+	}
+	return nil
+}
+
+// send: Sunucu mesajını sürüm ve türle birlikte yazma kuyruğuna ekler; beklemez
+func (lc *liveConn) send(msgType string, fields map[string]interface{}) error {
+	return lc.enqueue(message(msgType, fields), false)
+}
+
+// sendWait: Kuyrukta yer açılana kadar bekler. Sadece bağlantıya ait goroutine'den (izleyici
+// yayını gibi) çağrılır; segment worker'ları ve oturum kilidi altındaki kod send kullanır.
+func (lc *liveConn) sendWait(msgType string, fields map[string]interface{}) error {
+	return lc.enqueue(message(msgType, fields), true)
+}
+
+func message(msgType string, fields map[string]interface{}) map[string]interface{} {
+	msg := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		msg[k] = v
+	}
+	msg["v"] = protocolVersion
+	msg["type"] = msgType
+	return msg
+}
+
+// sendLogged: Yazma hatası oturumu durdurmaz; sadece loglanır (kapanmış bağlantı için sessizce)
+func (lc *liveConn) sendLogged(log *slog.Logger, msgType string, fields map[string]interface{}) {
+	if err := lc.send(msgType, fields); errors.Is(err, errConnClosed) {
+		log.Debug("connection closed, message dropped", "message_type", msgType)
+	} else if err != nil {
+		log.Warn("websocket write failed", "message_type", msgType, "error", err)
+	}
+}
+
+// ack: Kontrol mesajının işlendiğini bildirir
+func (lc *liveConn) ack(log *slog.Logger, msg clientMessage, fields map[string]interface{}) {
+	out := map[string]interface{}{"ack": msg.Type}
+	if msg.ID != "" {
+		out["id"] = msg.ID
+	}
+	for k, v := range fields {
+		out[k] = v
+	}
+	lc.sendLogged(log, msgAck, out)
+}
+
+// sendError: İstemcinin hatalı mesajı veya oturum hatası
+func (lc *liveConn) sendError(log *slog.Logger, code string, err error) {
+	lc.sendLogged(log, msgError, map[string]interface{}{"code": code, "message": err.Error()})
+}
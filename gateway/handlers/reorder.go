@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"gateway/metrics"
+)
+
+// reorderBuffer: Paralel işlenen segmentlerin sonuçlarını sıra numarasına göre yayınlar.
+// Uzun bir segmentin Whisper çağrısı sonrakilerden yavaş kalsa bile istemci sonuçları konuşma sırasıyla alır.
+// Sıradaki segment, bir worker işe başladıktan sonra timeout içinde bitmezse beklenmeden atlanır; sonucu
+// sonradan gelirse "late" olarak hemen yayınlanır. Worker kuyruğunda beklenen süre timeout'a sayılmaz.
+type reorderBuffer struct {
+	mu      sync.Mutex
+	log     *slog.Logger
+	timeout time.Duration
+	// mu tutulurken çağrılır; yayın sırası korunur. Mesajlar sadece bağlantıların yazma kuyruğuna
+	// eklenir, ağ yazması yapılmaz (okumayan bir istemci worker'ı bekletemez).
+	emit func(msgs []outMessage, late bool)
+
+	next    int                  // Yayınlanacak sıradaki segment
+	started map[int]time.Time    // Dispatch edilmiş, henüz yayınlanmamış segmentler; işleme başlama anı (sıfır: kuyrukta)
+	done    map[int][]outMessage // Bitmiş, sırasını bekleyen segmentlerin sonuçları
+	skipped map[int]bool         // Zaman aşımıyla atlananlar
+	timer   *time.Timer
+}
+
+func newReorderBuffer(log *slog.Logger, timeout time.Duration, emit func([]outMessage, bool)) *reorderBuffer {
+	return &reorderBuffer{
+		log:     log,
+		timeout: timeout,
+		emit:    emit,
+		next:    1,
+		started: make(map[int]time.Time),
+		done:    make(map[int][]outMessage),
+		skipped: make(map[int]bool),
+	}
+}
+
+// add: Segment analize gönderildi (sıra numaraları 1'den başlayıp artarak verilir); worker bekliyor olabilir
+func (b *reorderBuffer) add(seq int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.started[seq] = time.Time{}
+}
+
+// begin: Bir worker segmenti işlemeye başladı; zaman aşımı bu andan itibaren sayılır
+func (b *reorderBuffer) begin(seq int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, pending := b.started[seq]; !pending {
+		return
+	}
+	b.started[seq] = time.Now()
+	if seq == b.next {
+		b.arm()
+	}
+}
+
+// complete: Segmentin tüm sonuçları hazır (hata durumunda boş olabilir)
+func (b *reorderBuffer) complete(seq int, msgs []outMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.skipped[seq] {
+		delete(b.skipped, seq)
+		b.log.Warn("late segment result delivered out of order", "seq", seq)
+		b.emit(msgs, true)
+		return
+	}
+	delete(b.started, seq)
+	b.done[seq] = msgs
+	b.release()
+}
+
+// release: Sırası gelen sonuçları yayınlar ve zamanlayıcıyı yeni sıradakine göre kurar. mu tutulurken çağrılır.
+func (b *reorderBuffer) release() {
+	for {
+		msgs, ok := b.done[b.next]
+		if !ok {
+			break
+		}
+		delete(b.done, b.next)
+		b.emit(msgs, false)
+		b.next++
+	}
+	b.arm()
+}
+
+// arm: Sıradaki segment işleniyorsa ve arkasında bekleyen sonuç varsa zaman aşımı zamanlayıcısını
+// kurar. Henüz kuyrukta bekleyen segment için kurulmaz; begin kurar. mu tutulurken çağrılır.
+func (b *reorderBuffer) arm() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	startedAt, blocked := b.started[b.next]
+	if !blocked || startedAt.IsZero() || len(b.done) == 0 {
+		return
+	}
+	head := b.next
+	b.timer = time.AfterFunc(time.Until(startedAt.Add(b.timeout)), func() { b.expire(head) })
+}
+
+// expire: Sıradaki segment hâlâ bitmediyse atlanır
+func (b *reorderBuffer) expire(head int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.next != head {
+		return
+	}
+	if _, pending := b.started[head]; !pending {
+		return
+	}
+	b.log.Warn("segment result timed out, delivering later segments first", "seq", head, "timeout", b.timeout.String())
+	metrics.ReorderTimeouts.Inc()
+	delete(b.started, head)
+	b.skipped[head] = true
+	b.next++
+	b.release()
+}
+
+// interim: Segmentin nihai sonucu henüz yayınlanmadıysa publish'i çağırır; ara sonuç nihai
+// sonuçtan sonra istemciye ulaşıp onu eskitmesin diye yayın mu tutulurken yapılır.
+func (b *reorderBuffer) interim(seq int, publish func()) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, finished := b.done[seq]; finished || seq < b.next {
+		return false
+	}
+	publish()
+	return true
+}
+
+// stop: Oturum bitti; bekleyen zamanlayıcı iptal edilir
+func (b *reorderBuffer) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Routes: Gateway'in tüm rotaları (main ve uçtan uca testler aynı tabloyu kullanır)
+func (h *Handler) Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// WebSocket
+	mux.HandleFunc("/ws", h.HandleLiveAudio)
+	mux.HandleFunc("/ws/sessions/{id}/watch", h.HandleWatchSession)
+
+	// REST API
+	mux.HandleFunc("/api/users", HandleGetUsers)
+	mux.HandleFunc("/api/record_user", h.HandleRecordUser)
+	mux.HandleFunc("/api/records", HandleGetRecords)
+	mux.HandleFunc("/api/records/upload", h.HandleUploadRecord)
+	mux.HandleFunc("/api/segments", HandleGetSegments)
+	mux.HandleFunc("/api/sessions/active", HandleGetActiveSessions)
+
+	// Sağlık kontrolleri (orkestratör ve frontend için)
+	mux.HandleFunc("/healthz", HandleHealthz)
+	mux.HandleFunc("/readyz", h.HandleReadyz)
+
+	// Prometheus metrikleri
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return mux
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"gateway/config"
+
+	"github.com/maxhawkins/go-webrtcvad"
+)
+
+// forceCutWindow: Maksimum süre aşıldığında kesim noktasının arandığı son ses aralığı
+const forceCutWindow = 2 * time.Second
+
+// track: Tek bir ses kanalının VAD ve segmentasyon durumu. Kanal bazlı ayrıştırmada her kanal
+// kendi VAD'iyle bağımsız segmentlenir; bütün kanallar aynı zaman çizelgesinde ilerler.
+type track struct {
+	channel        int // 1'den başlar; 0: mono oturum
+	vad            *webrtcvad.VAD
+	audioBuffer    []byte
+	currentSegment []byte
+	frames         []frameInfo // currentSegment'in çerçeve başına VAD/enerji özeti
+	preRoll        []byte      // Konuşma beklenirken son pre_roll kadar ses
+	preRollFrames  []frameInfo
+	isSpeaking     bool
+	silenceCounter int
+	bytesProcessed int
+	interimBytes   int         // Son ara transkripsiyonda currentSegment uzunluğu
+	interimBusy    atomic.Bool // Ara transkripsiyon sürüyor; bitmeden yenisi istenmez
+}
+
+// resetSegment: Segment gönderildi; yeni konuşma bekleniyor. Pre-roll da temizlenir:
+// gönderilen segmentteki (veya duraklatmadan önceki) ses bir sonrakinin başına tekrar eklenmez.
+func (t *track) resetSegment() {
+	t.currentSegment = nil
+	t.frames = nil
+	t.preRoll = nil
+	t.preRollFrames = nil
+	t.isSpeaking = false
+	t.silenceCounter = 0
+	t.interimBytes = 0
+}
+
+// segmentOffset: currentSegment'in (pre-roll dahil) oturum zaman çizelgesindeki başlangıcı.
+// Whisper zamanları gönderilen sesin başına göre döndüğü için segment sonuçları buna eklenir.
+func (t *track) segmentOffset() float64 {
+	return float64(t.bytesProcessed-len(t.currentSegment)) / float64(config.C.BytesPerSecond())
+}
+
+// frameInfo: currentSegment'teki bir VAD çerçevesinin özeti (kesim noktası seçimi için)
+type frameInfo struct {
+	active bool    // VAD konuşma dedi
+	energy float64 // RMS
+}
+
+// frameEnergy: 16-bit little-endian PCM çerçevesinin RMS değeri
+func frameEnergy(frame []byte) float64 {
+	n := len(frame) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(frame[2*i:])))
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// quietestFrame: Son forceCutWindow içindeki en sessiz çerçevenin indeksi. VAD'in sessiz dediği
+// çerçeveler konuşma çerçevelerine tercih edilir, eşitlikte enerjisi düşük olan, o da eşitse
+// daha geç olan seçilir. Kesim minFrames'ten önceye düşmez; böylece baştaki parça da minimum
+// segment süresini karşılar.
+func quietestFrame(frames []frameInfo, windowFrames, minFrames int) int {
+	from := max(len(frames)-windowFrames, minFrames, 0)
+	best := len(frames) - 1
+	for i := from; i < len(frames); i++ {
+		f, b := frames[i], frames[best]
+		switch {
+		case f.active != b.active:
+			if !f.active {
+				best = i
+			}
+		case f.energy <= b.energy:
+			best = i
+		}
+	}
+	return best
+}
+
+// keepPreRoll: Konuşma beklenirken çerçeveyi pre-roll tamponuna ekler; en eski çerçeveler düşer
+func (t *track) keepPreRoll(policy config.SegmentationPolicy, frame []byte, info frameInfo) {
+	limit := config.C.DurationBytes(policy.PreRoll.Duration) / len(frame)
+	if limit == 0 {
+		return
+	}
+	t.preRoll = append(t.preRoll, frame...)
+	t.preRollFrames = append(t.preRollFrames, info)
+	if drop := len(t.preRollFrames) - limit; drop > 0 {
+		t.preRoll = append(t.preRoll[:0], t.preRoll[drop*len(frame):]...)
+		t.preRollFrames = append(t.preRollFrames[:0], t.preRollFrames[drop:]...)
+	}
+}
+
+// forceCut: Konuşma maksimum segment süresini aştı. Segment son aralıktaki en sessiz çerçevenin
+// sonundan kesilip analize gönderilir; sonrası yeni segmentin başı olarak kalır (kelime ortasından
+// kesmemek için). Kalan ses konuşma olarak devam ettiği için isSpeaking korunur.
+func (h *Handler) forceCut(s *liveSession, t *track) {
+	packet := config.C.PacketSize
+	windowFrames := int(forceCutWindow.Seconds() * float64(config.C.BytesPerSecond()) / float64(packet))
+	minFrames := config.C.DurationBytes(s.policy.MinSegment.Duration) / packet
+	cut := quietestFrame(t.frames, windowFrames, minFrames) + 1
+
+	h.dispatch(s, t, "max_length", t.currentSegment[:cut*packet], t.segmentOffset())
+
+	t.currentSegment = append([]byte(nil), t.currentSegment[cut*packet:]...)
+	t.frames = append([]frameInfo(nil), t.frames[cut:]...)
+	t.silenceCounter = min(t.silenceCounter, len(t.frames))
+	t.interimBytes = 0
+}
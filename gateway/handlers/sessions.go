@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gateway/config"
+
+	"github.com/maxhawkins/go-webrtcvad"
+)
+
+// newSessionID: Global olarak tekil oturum kimliği.
+// Zaman önekiyle sıralanabilir kalır; aynı saniyede açılan oturumları 64 bit rastgele ek ayırır.
+func newSessionID() string {
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		// crypto/rand Linux'ta hata dönmez; yine de nanosaniye ile tekilliği koru
+		return fmt.Sprintf("sess_%d_%d", time.Now().Unix(), time.Now().UnixNano())
+	}
+	return fmt.Sprintf("sess_%d_%s", time.Now().Unix(), hex.EncodeToString(suffix[:]))
+}
+
+// newToken: Oturuma ait, tahmin edilemez erişim anahtarı (128 bit)
+func newToken() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validToken: Verilen anahtarı sabit sürede karşılaştırır
+func validToken(want, got string) bool {
+	return subtle.ConstantTimeCompare([]byte(want), []byte(got)) == 1
+}
+
+// errDetached: Oturumun o an bağlı bir istemcisi yok (bağlantı koptu, devam ettirilmesi bekleniyor)
+var errDetached = errors.New("session has no connected client")
+
+// errSessionFinished: İzlenmek istenen oturum bu arada tamamlandı
+var errSessionFinished = errors.New("oturum tamamlandı")
+
+// liveSession: Bir kaydın canlı analiz durumu. Bağlantıdan bağımsızdır; bağlantı koparsa
+// resume_grace süresince saklanır ve aynı ID ile yeniden bağlanan istemci kaldığı yerden devam eder.
+type liveSession struct {
+	ID        string
+	StartedAt time.Time
+	Settings  sessionSettings
+
+	// İzleyicilerin /ws/sessions/{id}/watch için vermesi gereken anahtar; sadece session_started ile
+	// oturumu açan istemciye (yüklemede yanıtta) verilir
+	watchToken string
+	// Devam ettirme için resume_session_id ile birlikte istenir; oturum kimliği listelendiği için
+	// tek başına oturumu devralmaya yetmez. Sadece session_started ile verilir.
+	resumeToken string
+
+	ctx    context.Context // record_id taşıyan oturum context'i (bağlantıdan bağımsız)
+	log    *slog.Logger
+	policy config.SegmentationPolicy // Settings'ten; oturum boyunca değişmez
+
+	bytesReceived   atomic.Int64
+	segmentsEmitted atomic.Int64
+	queueDepth      atomic.Int64 // Analizi süren segment sayısı
+
+	// Ses işleme durumu: sadece o an bağlı olan bağlantının okuma döngüsü (veya yüklenen kaydı işleyen goroutine) erişir
+	tracks     []*track // Mono oturumda tek; kanal bazlı ayrıştırmada kanal başına bir tane
+	speakers   []string // Kanal bazlı ayrıştırmada kanal başına konuşmacı adı
+	segmentSeq int      // Tüm kanallarda ortak; sonuçlar bu sırayla yayınlanır
+	paused     bool
+	upload     bool           // Yüklenen dosyadan besleniyor: istemci yok, ara transkripsiyon yapılmaz
+	wg         sync.WaitGroup // Analizi süren segmentler
+	slots      chan struct{}  // Oturum kuyruğu: bekleyen + işlenen segment başına bir yer
+	reorder    *reorderBuffer // Sonuçları segment sırasıyla yayınlar
+
+	conn atomic.Pointer[liveConn] // nil: istemci bağlı değil
+
+	// İzleyiciler ve yayın geçmişi (mu ile korunur)
+	mu         sync.Mutex
+	spectators map[*spectator]struct{}
+	history    []outMessage // Sonradan katılanlar ve devam ettirmede tekrar gönderim için son historyLimit live_analysis
+	delivered  int          // history'nin istemcinin yazma kuyruğuna verilmiş kısmı
+	finished   bool
+
+	// registry kilidi ile korunur
+	clientAddr string
+	reconnects int
+	timer      *time.Timer // Askıdayken grace süresi zamanlayıcısı
+	expire     func()      // Askı devam ettirilmeden biterse oturumu sonlandırır
+}
+
+// registry: Bağlı veya devam ettirilmeyi bekleyen tüm oturumlar
+var registry = struct {
+	sync.Mutex
+	sessions map[string]*liveSession
+}{sessions: make(map[string]*liveSession)}
+
+func newLiveSession(ctx context.Context, log *slog.Logger, id string, settings sessionSettings) *liveSession {
+	s := &liveSession{
+		ID:          id,
+		StartedAt:   time.Now(),
+		Settings:    settings,
+		watchToken:  newToken(),
+		resumeToken: newToken(),
+		policy:      settings.policy(),
+		ctx:         ctx,
+		log:         log,
+		spectators:  make(map[*spectator]struct{}),
+		slots:       make(chan struct{}, config.C.SessionQueueLimit),
+		speakers:    settings.channelSpeakers(),
+	}
+	if settings.Diarization == diarizationChannel {
+		for ch := 1; ch <= settings.Channels; ch++ {
+			s.tracks = append(s.tracks, &track{channel: ch})
+		}
+	} else {
+		s.tracks = []*track{{}}
+	}
+	s.resetVAD()
+	s.reorder = newReorderBuffer(log, config.C.ReorderTimeout.Duration, s.emitResults)
+
+	registry.Lock()
+	registry.sessions[id] = s
+	registry.Unlock()
+	return s
+}
+
+// resetVAD: Her kanal için yeni VAD örneği (oturum başı veya atlanan sesten sonra iç durum temizlenir)
+func (s *liveSession) resetVAD() {
+	for _, t := range s.tracks {
+		t.vad, _ = webrtcvad.New()
+		t.vad.SetMode(s.Settings.VADAggressiveness)
+	}
+}
+
+// attach: Bağlantıyı oturuma bağlar
+func (s *liveSession) attach(lc *liveConn, clientAddr string) {
+	registry.Lock()
+	s.clientAddr = clientAddr
+	registry.Unlock()
+	lc.setLogger(s.log.With("remote_addr", clientAddr))
+	s.conn.Store(lc)
+}
+
+// reattach: Devam ettirilen oturuma yeni bağlantıyı bağlar, session_started'ı gönderir ve istemcinin
+// almadığı live_analysis mesajlarını "replayed": true ile tekrar gönderir. s.mu altında yapılır;
+// bu sırada yayınlanan sonuç ne kaybolur ne de iki kez gönderilir. Tekrar gönderilen mesaj sayısını döner.
+func (s *liveSession) reattach(lc *liveConn, clientAddr string, lastSeq *int, started map[string]interface{}) int {
+	registry.Lock()
+	s.clientAddr = clientAddr
+	registry.Unlock()
+
+	lc.setLogger(s.log.With("remote_addr", clientAddr))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn.Store(lc)
+	missed := s.missedResults(lastSeq)
+	started["replayed"] = len(missed)
+	lc.send(msgSessionStarted, started)
+	for _, msg := range missed {
+		fields := make(map[string]interface{}, len(msg.fields)+1)
+		for k, v := range msg.fields {
+			fields[k] = v
+		}
+		fields["replayed"] = true
+		lc.send(msg.msgType, fields)
+	}
+	s.delivered = len(s.history)
+	return len(missed)
+}
+
+// missedResults: Devam eden istemcinin almadığı live_analysis mesajları (yayın sırasıyla). lastSeq
+// verilirse geçmişte o segmentin mesajlarından sonra yayınlananlar (bulunamazsa seq'i daha büyük
+// olanlar); verilmezse kuyruğa hiç verilemeyenler. s.mu tutulurken çağrılır.
+func (s *liveSession) missedResults(lastSeq *int) []outMessage {
+	if lastSeq == nil {
+		return s.history[s.delivered:]
+	}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].fields["seq"] == *lastSeq {
+			return s.history[i+1:]
+		}
+	}
+	var missed []outMessage
+	for _, msg := range s.history {
+		if seq, _ := msg.fields["seq"].(int); seq > *lastSeq {
+			missed = append(missed, msg)
+		}
+	}
+	return missed
+}
+
+// send: Oturumun o anki bağlantısına yazar; istemci bağlı değilse errDetached döner
+func (s *liveSession) send(msgType string, fields map[string]interface{}) error {
+	lc := s.conn.Load()
+	if lc == nil {
+		return errDetached
+	}
+	return lc.send(msgType, fields)
+}
+
+func (s *liveSession) sendLogged(log *slog.Logger, msgType string, fields map[string]interface{}) {
+	logSendError(log, msgType, s.send(msgType, fields))
+}
+
+// logSendError: İstemcisi bağlı olmayan oturumda mesajın düşmesi beklenen durumdur; sadece debug
+func logSendError(log *slog.Logger, msgType string, err error) {
+	if errors.Is(err, errDetached) || errors.Is(err, errConnClosed) {
+		log.Debug("client detached, message dropped", "message_type", msgType)
+	} else if err != nil {
+		log.Warn("websocket write failed", "message_type", msgType, "error", err)
+	}
+}
+
+// suspend: Bağlantı koptu; oturum grace süresi boyunca devam ettirilmeyi bekler.
+// Süre dolarsa (veya sunucu kapanırsa) expire çağrılır. Askıdaki oturum kapanışta beklenir;
+// bu yüzden sadece kayıtlı bir bağlantının içinden çağrılmalı (lifecycle sayacı sıfıra düşmemiş olmalı).
+// Sunucu kapanıyorsa askıya alınmaz ve false döner; çağıran oturumu hemen sonlandırır.
+// Kapanış kontrolü, bağlantının bırakılması ve zamanlayıcı registry kilidi altında birlikte yapılır:
+// expireSuspended bu oturumu ya askıda görür ya da suspend kapanışı görür; devam ettirme de
+// bağlantısı bırakılmış ama zamanlayıcısı henüz kurulmamış bir oturumla karşılaşmaz (kilit sırası: registry → lifecycle).
+func (s *liveSession) suspend(expire func()) bool {
+	registry.Lock()
+	defer registry.Unlock()
+	s.conn.Store(nil)
+	if isDraining() {
+		return false
+	}
+	lifecycle.wg.Add(1)
+	s.expire = expire
+	s.timer = time.AfterFunc(config.C.ResumeGrace.Duration, func() {
+		if takeTimer(s) {
+			expire()
+		}
+	})
+	return true
+}
+
+// takeTimer: Askıdaki oturumun sahipliğini alır (devam ettirme, süre dolumu veya kapanış;
+// hangisi önce gelirse). Sahiplik alındığında askı için ayrılan lifecycle sayacı çağırana geçer.
+func takeTimer(s *liveSession) bool {
+	registry.Lock()
+	defer registry.Unlock()
+	if s.timer == nil {
+		return false
+	}
+	s.timer.Stop()
+	s.timer = nil
+	return true
+}
+
+// resumeSession: Askıdaki oturumu devam ettirmek üzere döner; yoksa, anahtar yanlışsa, bağlıysa veya
+// süresi dolduysa nil. Anahtar askı devralınmadan önce denetlenir ki yanlış denemeler oturumu bozmasın.
+func resumeSession(id, token string) *liveSession {
+	registry.Lock()
+	s := registry.sessions[id]
+	registry.Unlock()
+	if s == nil || !validToken(s.resumeToken, token) || !takeTimer(s) {
+		return nil
+	}
+	// Askının lifecycle sayacı bırakılır; oturumu artık devralan bağlantı tutar
+	lifecycle.wg.Done()
+
+	registry.Lock()
+	s.reconnects++
+	registry.Unlock()
+	return s
+}
+
+// expireSuspended: Kapanışta askıdaki tüm oturumları grace süresini beklemeden sonlandırır
+func expireSuspended() {
+	registry.Lock()
+	var suspended []*liveSession
+	for _, s := range registry.sessions {
+		if s.timer != nil {
+			suspended = append(suspended, s)
+		}
+	}
+	registry.Unlock()
+
+	for _, s := range suspended {
+		if takeTimer(s) {
+			go s.expire()
+		}
+	}
+}
+
+// unregister: Oturum tamamen bitti
+func (s *liveSession) unregister() {
+	registry.Lock()
+	delete(registry.sessions, s.ID)
+	registry.Unlock()
+}
+
+// activeSession: GET /api/sessions/active yanıtındaki tek oturum
+type activeSession struct {
+	ID              string    `json:"id"`
+	StartedAt       time.Time `json:"started_at"`
+	ClientAddr      string    `json:"client_addr"`
+	Connected       bool      `json:"connected"` // false: bağlantı koptu, devam ettirilmesi bekleniyor
+	Reconnects      int       `json:"reconnects"`
+	BytesReceived   int64     `json:"bytes_received"`
+	SegmentsEmitted int64     `json:"segments_emitted"` // İstemciye gönderilen live_analysis sayısı
+	QueueDepth      int64     `json:"queue_depth"`
+}
+
+// activeSessions: Kayıtlı oturumların anlık görüntüsü (en eski önce)
+func activeSessions() []activeSession {
+	registry.Lock()
+	out := make([]activeSession, 0, len(registry.sessions))
+	for _, s := range registry.sessions {
+		out = append(out, activeSession{
+			ID:              s.ID,
+			StartedAt:       s.StartedAt,
+			ClientAddr:      s.clientAddr,
+			Connected:       s.conn.Load() != nil,
+			Reconnects:      s.reconnects,
+			BytesReceived:   s.bytesReceived.Load(),
+			SegmentsEmitted: s.segmentsEmitted.Load(),
+			QueueDepth:      s.queueDepth.Load(),
+		})
+	}
+	registry.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StartedAt.Equal(out[j].StartedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].StartedAt.Before(out[j].StartedAt)
+	})
+	return out
+}
+
+// GET /api/sessions/active
+func HandleGetActiveSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(activeSessions())
+}
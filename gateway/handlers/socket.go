@@ -1,202 +1,634 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sort"
 	"strings" // Metin birleştirme için eklendi
-	"sync"
 	"time"
 
+	"gateway/audio"
+	"gateway/config"
 	"gateway/database"
+	"gateway/logging"
+	"gateway/metrics"
 	"gateway/models"
 	"gateway/services"
+	"gateway/tracing"
 
 	"github.com/gorilla/websocket"
-	"github.com/maxhawkins/go-webrtcvad"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-func HandleLiveAudio(w http.ResponseWriter, r *http.Request) {
+// sessionEnd: Okuma döngüsünün neden bittiği
+type sessionEnd int
+
+const (
+	endStop       sessionEnd = iota // İstemci stop gönderdi: bağlantı session_complete'e kadar açık kalır
+	endShutdown                     // Sunucu kapanıyor: tampon işlenir, istemci bilgilendirilir
+	endDisconnect                   // Bağlantı koptu: oturum devam ettirilmeyi bekler
+)
+
+func (h *Handler) HandleLiveAudio(w http.ResponseWriter, r *http.Request) {
+	// Kapanış sırasında yeni oturum açılmaz
+	if isDraining() {
+		http.Error(w, "Sunucu kapanıyor", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("WS Upgrade Error:", err)
+		slog.Warn("websocket upgrade failed", "remote_addr", r.RemoteAddr, "error", err)
 		return
 	}
 	defer conn.Close()
 
-	sessionID := fmt.Sprintf("sess_%d", time.Now().Unix())
-	log.Printf("Canlı analiz başladı: %s", sessionID)
-
-	newRecord := models.Record{
-		ID:   sessionID,
-		Date: time.Now(),
+	lc := newLiveConn(conn)
+	defer lc.close(0, "") // Yazıcı goroutine'i her çıkışta durur; kapanış çerçevesi gerekiyorsa önce gönderilir
+	if !beginSession(lc) {
+		lc.close(websocket.CloseTryAgainLater, "Sunucu kapanıyor")
+		return
 	}
-	database.DB.Create(&newRecord)
-
-	vad, _ := webrtcvad.New()
-	vad.SetMode(3)
+	defer endSession(lc)
 
-	var (
-		audioBuffer    []byte
-		currentSegment []byte
-		isSpeaking     bool
-		silenceCounter int
-		bytesProcessed int
-		wg             sync.WaitGroup
-	)
+	metrics.SessionsActive.Inc()
+	defer metrics.SessionsActive.Dec()
 
-	connLock := &sync.Mutex{}
-
-	for {
-		msgType, data, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("Bağlantı kesildi veya hata:", err)
-			break
+	// El sıkışma: İlk mesaj JSON "start" ise ayarlar doğrulanır ve ses akmadan önce oturum kimliğiyle onaylanır.
+	// Eski istemciler doğrudan ses (veya STOP) gönderir; bu mesaj okuma döngüsüne ilk mesaj olarak aktarılır.
+	firstType, first, err := conn.ReadMessage()
+	if err != nil {
+		slog.Info("connection closed before session start", "remote_addr", r.RemoteAddr, "error", err)
+		return
+	}
+	start := startMessage{Settings: defaultSettings()}
+	handshake := false
+	if firstType == websocket.TextMessage {
+		st, ok, err := parseStart(first)
+		if ok && err != nil {
+			slog.Warn("invalid start message, session rejected", "remote_addr", r.RemoteAddr, "error", err)
+			reject(lc, "invalid_start", err, "Geçersiz start mesajı")
+			return
 		}
+		if ok {
+			start, handshake = st, true
+		}
+	}
 
-		if msgType == websocket.TextMessage && string(data) == "STOP" {
-			log.Println("Durdurma isteği alındı, tampon temizleniyor...")
-			if len(currentSegment) > 0 {
-				segmentCopy := make([]byte, len(currentSegment))
-				copy(segmentCopy, currentSegment)
-				offsetSec := float64(bytesProcessed-len(currentSegment)) / float64(models.SampleRate*2)
+	var sess *liveSession
+	if start.ResumeSessionID != "" {
+		sess = resumeSession(start.ResumeSessionID, start.ResumeToken)
+		if sess == nil {
+			slog.Warn("session resume rejected", logging.KeyRecordID, start.ResumeSessionID, "remote_addr", r.RemoteAddr)
+			reject(lc, "resume_failed",
+				fmt.Errorf("oturum %q devam ettirilemez (bilinmiyor, anahtar geçersiz, hâlâ bağlı veya süresi doldu)", start.ResumeSessionID),
+				"Oturum devam ettirilemez")
+			return
+		}
+		replayed := sess.reattach(lc, r.RemoteAddr, start.LastSeq, map[string]interface{}{
+			"session_id":   sess.ID,
+			"settings":     sess.Settings,
+			"resumed":      true,
+			"offset_sec":   sess.offsetSec(),
+			"watch_token":  sess.watchToken,
+			"resume_token": sess.resumeToken,
+		})
+		sess.log.Info("live session resumed", "remote_addr", r.RemoteAddr, "offset_sec", sess.offsetSec(), "replayed", replayed)
+	} else {
+		sess = newRecordSession(start.Settings, handshake, r.RemoteAddr)
+		sess.attach(lc, r.RemoteAddr)
+		if handshake {
+			sess.sendLogged(sess.log, msgSessionStarted, map[string]interface{}{
+				"session_id":   sess.ID,
+				"settings":     sess.Settings,
+				"resumed":      false,
+				"offset_sec":   sess.offsetSec(),
+				"watch_token":  sess.watchToken,
+				"resume_token": sess.resumeToken,
+			})
+		}
+	}
 
-				wg.Add(1)
-				go processAndRespond(sessionID, segmentCopy, offsetSec, conn, connLock, &wg)
+	next := conn.ReadMessage
+	if !handshake {
+		replayed := false
+		next = func() (int, []byte, error) {
+			if !replayed {
+				replayed = true
+				return firstType, first, nil
 			}
-			break
+			return conn.ReadMessage()
 		}
+	}
 
-		if msgType != websocket.BinaryMessage {
-			continue
+	end := h.readLoop(lc, sess, next)
+
+	if end == endDisconnect {
+		// Kopan istemci aynı ID ile dönebilir. session_id'yi hiç almamış eski istemcinin oturumu (el sıkışma
+		// yok) devam ettirilemez; o, grace süresi kapalıysa veya sunucu kapanıyorsa oturum hemen biter.
+		suspended := handshake && config.C.ResumeGrace.Duration > 0 && sess.suspend(func() {
+			defer lifecycle.wg.Done()
+			sess.log.Info("resume grace expired")
+			h.finishSession(sess, "disconnect")
+		})
+		if suspended {
+			sess.log.Info("connection lost, session suspended", "resume_grace", config.C.ResumeGrace.String())
+		} else {
+			sess.conn.Store(nil)
+			// Kapanışta yarıda kalmaması için lifecycle tarafından takip edilir
+			goBackground(func() { h.finishSession(sess, "disconnect") })
 		}
+		return
+	}
+
+	// İstemci hâlâ bağlı (stop veya kapanış): işlenmeyi bekler, sonuç session_complete ile bildirilir.
+	// Oturum hâlâ kayıtlı olduğu için kapanışta beklenir.
+	sess.sendStatus(stateProcessing)
+	h.finishSession(sess, "stop")
+
+	closeCode, reason := websocket.CloseNormalClosure, "Oturum tamamlandı"
+	if end == endShutdown {
+		closeCode, reason = websocket.CloseGoingAway, "Sunucu kapanıyor"
+	}
+	lc.close(closeCode, reason)
+}
 
-		audioBuffer = append(audioBuffer, data...)
+// newRecordSession: Yeni oturum ve ona ait models.Record
+func newRecordSession(settings sessionSettings, handshake bool, clientAddr string) *liveSession {
+	id := newSessionID()
+	metrics.SessionsTotal.Inc()
 
-		for len(audioBuffer) >= models.PacketSize {
-			frame := audioBuffer[:models.PacketSize]
-			frameStart := bytesProcessed
-			bytesProcessed += models.PacketSize
-			audioBuffer = audioBuffer[models.PacketSize:]
+	// Oturum boyunca üretilen tüm log satırları record_id taşır.
+	// İstek context'i handler dönünce iptal olduğu için konu analizi de sürebilsin diye Background kullanılır.
+	ctx, log := logging.With(context.Background(), logging.KeyRecordID, id)
+	log.Info("live session started", "remote_addr", clientAddr, "handshake", handshake, "settings", settings)
 
-			active, err := vad.Process(models.SampleRate, frame)
+	sess := newLiveSession(ctx, log, id, settings)
+	newRecord := models.Record{
+		ID:                   id,
+		Date:                 sess.StartedAt,
+		Title:                settings.Title,
+		Language:             settings.Language,
+		SampleRate:           settings.SampleRate,
+		Channels:             settings.Channels,
+		Diarization:          settings.Diarization,
+		Encoding:             settings.Encoding,
+		VADAggressiveness:    settings.VADAggressiveness,
+		ExpectedParticipants: settings.ExpectedParticipants,
+	}
+	if err := database.DB.Create(&newRecord).Error; err != nil {
+		log.Error("record create failed", "error", err)
+	}
+	return sess
+}
+
+// reject: Oturum açılmadan hata bildirir ve bağlantıyı kapatır
+func reject(lc *liveConn, code string, err error, closeReason string) {
+	lc.send(msgError, map[string]interface{}{"code": code, "message": err.Error()})
+	lc.close(websocket.ClosePolicyViolation, closeReason)
+}
+
+// readLoop: Bağlantı açık kaldıkça ses ve kontrol mesajlarını işler
+func (h *Handler) readLoop(lc *liveConn, s *liveSession, next func() (int, []byte, error)) sessionEnd {
+	log := s.log
+	in, err := newAudioInput(s)
+	if err != nil {
+		log.Error("audio decoder start failed", "encoding", s.Settings.Encoding, "error", err)
+		lc.sendError(log, "decode_failed", err)
+		return endStop
+	}
+	defer in.cancel()
+
+	// Mesajlar ayrı goroutine'de okunur; döngü mesaj beklerken çözülen sesi de işleyebilir
+	msgs := make(chan wsMessage)
+	quit := make(chan struct{})
+	defer close(quit)
+	go readMessages(next, msgs, quit)
+
+	for {
+		var m wsMessage
+		select {
+		case <-in.ready():
+			done, err := h.drain(s, in)
+			if !done {
+				continue
+			}
+			// Akış bitti veya bozuk: bağlantıdaki ses artık çözülemez, oturum stop gibi tamamlanır
 			if err != nil {
+				log.Warn("audio stream decode failed, ending session", "error", err)
+				lc.sendError(log, "decode_failed", err)
+			} else {
+				log.Info("audio stream ended, ending session")
+			}
+			h.flush(s, "stop")
+			return endStop
+		case m = <-msgs:
+		}
+		msgType, data := m.msgType, m.data
+
+		if m.err != nil {
+			h.closeInput(s, in)
+			if lc.shuttingDown.Load() {
+				log.Info("server shutting down, flushing buffer")
+				h.flush(s, "shutdown")
+				return endShutdown
+			}
+			log.Info("connection closed", "error", m.err)
+			return endDisconnect
+		}
+
+		if msgType == websocket.TextMessage {
+			msg, err := parseClientMessage(data)
+			if err != nil {
+				log.Warn("invalid control message", "error", err)
+				lc.sendError(log, "bad_message", err)
 				continue
 			}
 
-			if active {
-				isSpeaking = true
-				silenceCounter = 0
-				currentSegment = append(currentSegment, frame...)
-			} else {
-				silenceCounter++
-				if isSpeaking {
-					currentSegment = append(currentSegment, frame...)
+			switch msg.Type {
+			case msgStop:
+				log.Info("stop requested, flushing buffer")
+				h.closeInput(s, in)
+				h.flush(s, "stop")
+				lc.ack(log, msg, nil)
+				return endStop
+			case msgPause:
+				if !s.paused {
+					log.Info("session paused")
+					h.flush(s, "pause")
+					s.paused = true
 				}
+				lc.ack(log, msg, nil)
+				s.sendStatus(statePaused)
+			case msgResume:
+				if s.paused {
+					log.Info("session resumed")
+					s.paused = false
+					// VAD'in iç durumu duraklatmadan önceki sese ait; atlanan sesle karışmasın
+					s.resetVAD()
+				}
+				lc.ack(log, msg, nil)
+				s.sendStatus(stateRecording)
+			case msgPing:
+				lc.ack(log, msg, map[string]interface{}{"server_time": time.Now()})
+			case msgMark:
+				t := s.tracks[0]
+				offsetSec := float64(t.bytesProcessed+len(t.audioBuffer)) / float64(config.C.BytesPerSecond())
+				log.Info("mark", "label", msg.Label, "offset_sec", offsetSec)
+				lc.ack(log, msg, map[string]interface{}{"label": msg.Label, "offset_sec": offsetSec})
+			case msgStart:
+				lc.sendError(log, "unexpected_start", errors.New("start sadece oturumun ilk mesajı olabilir"))
+			default:
+				lc.sendError(log, "unknown_type", fmt.Errorf("bilinmeyen mesaj türü %q", msg.Type))
 			}
+			continue
+		}
 
-			if silenceCounter > 25 && len(currentSegment) > models.MinSegmentBytes {
-				segmentCopy := make([]byte, len(currentSegment))
-				copy(segmentCopy, currentSegment)
-				offsetSec := float64(frameStart-len(currentSegment)) / float64(models.SampleRate*2)
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+		s.bytesReceived.Add(int64(len(data)))
+		h.write(s, in, data)
+	}
+}
 
-				wg.Add(1)
-				go processAndRespond(sessionID, segmentCopy, offsetSec, conn, connLock, &wg)
+// wsMessage: Okuma goroutine'inden gelen WebSocket mesajı (err: bağlantı bitti)
+type wsMessage struct {
+	msgType int
+	data    []byte
+	err     error
+}
 
-				currentSegment = nil
-				isSpeaking = false
-				silenceCounter = 0
-			}
+// readMessages: next'in döndürdüğü mesajları hata gelene veya quit kapanana kadar iletir
+func readMessages(next func() (int, []byte, error), msgs chan<- wsMessage, quit <-chan struct{}) {
+	for {
+		msgType, data, err := next()
+		select {
+		case msgs <- wsMessage{msgType: msgType, data: data, err: err}:
+		case <-quit:
+			return
+		}
+		if err != nil {
+			return
 		}
 	}
+}
 
-	// Tüm işlemlerin (Whisper/Sentiment vs) bitmesini bekle
-	wg.Wait()
-	log.Println("Analiz oturumu sonlandırıldı, konu analizi başlıyor:", sessionID)
+// processAudio: Kanalın PCM'ini VAD çerçevelerine böler ve sessizlikte segmenti keser
+func (h *Handler) processAudio(s *liveSession, t *track, data []byte) {
+	t.audioBuffer = append(t.audioBuffer, data...)
 
-	// --- KONU ANALİZİ (POST-PROCESSING) ---
-	go func() {
-		// 1. Bu kayıt için tüm segmentleri veritabanından çek
-		var segments []models.Segment
-		if err := database.DB.Where("record_id = ?", sessionID).Order("start_offset asc").Find(&segments).Error; err != nil {
-			log.Println("Segmentler çekilemedi:", err)
-			return
+	// Duraklatılmışken gelen ses analiz edilmez ama zaman çizelgesinde yer kaplar;
+	// böylece devam edildikten sonraki segment zamanları kayıtla hizalı kalır.
+	if s.paused {
+		skipped := len(t.audioBuffer) - len(t.audioBuffer)%config.C.PacketSize
+		t.bytesProcessed += skipped
+		t.audioBuffer = t.audioBuffer[skipped:]
+		return
+	}
+
+	hangoverFrames := config.C.DurationBytes(s.policy.SilenceHangover.Duration) / config.C.PacketSize
+	minBytes := config.C.DurationBytes(s.policy.MinSegment.Duration)
+	maxBytes := config.C.DurationBytes(s.policy.MaxSegment.Duration)
+
+	for len(t.audioBuffer) >= config.C.PacketSize {
+		frame := t.audioBuffer[:config.C.PacketSize]
+		t.bytesProcessed += config.C.PacketSize
+		t.audioBuffer = t.audioBuffer[config.C.PacketSize:]
+
+		active, err := t.vad.Process(config.SampleRate, frame)
+		if err != nil {
+			continue
 		}
+		info := frameInfo{active: active, energy: frameEnergy(frame)}
+
+		if active {
+			if !t.isSpeaking {
+				// Konuşma başladı: kelime başı kırpılmasın diye hemen önceki ses segmentin başına eklenir
+				t.currentSegment = append(t.currentSegment, t.preRoll...)
+				t.frames = append(t.frames, t.preRollFrames...)
+				t.preRoll, t.preRollFrames = nil, nil
+			}
+			t.isSpeaking = true
+			t.silenceCounter = 0
+		} else {
+			t.silenceCounter++
+		}
+		if !t.isSpeaking {
+			t.keepPreRoll(s.policy, frame, info)
+			continue
+		}
+		t.currentSegment = append(t.currentSegment, frame...)
+		t.frames = append(t.frames, info)
 
-		if len(segments) == 0 {
-			return
+		if t.silenceCounter > hangoverFrames && len(t.currentSegment) > minBytes {
+			h.dispatch(s, t, "silence", t.currentSegment, t.segmentOffset())
+			t.resetSegment()
+			continue
 		}
 
-		// 2. Metinleri birleştir
-		var fullTextBuilder strings.Builder
-		for _, seg := range segments {
-			fullTextBuilder.WriteString(seg.Text)
-			fullTextBuilder.WriteString(" ")
+		// Kesintisiz konuşma: segment sınırsız büyümesin
+		if len(t.currentSegment) >= maxBytes {
+			h.forceCut(s, t)
 		}
-		fullText := strings.TrimSpace(fullTextBuilder.String())
 
-		// 3. Konu Analizi Servisini Çağır
-		topic, err := services.CallTopicAnalysisService(fullText)
-		if err != nil {
-			log.Println("Konu analizi servisi hatası:", err)
-			topic = "Belirsiz"
+		// Ara sonuç, kesildiğinde alacağı seq'i önceden bilir; kanallar birbirinden bağımsız
+		// kesildiği için bu sadece tek kanallı oturumda mümkündür
+		if step := config.C.InterimBytes(); step > 0 && len(s.tracks) == 1 && !s.upload && t.isSpeaking && len(t.currentSegment)-t.interimBytes >= step {
+			h.interim(s, t)
 		}
+	}
+}
 
-		// 4. Kaydı güncelle
-		if err := database.DB.Model(&models.Record{}).Where("id = ?", sessionID).Update("topic", topic).Error; err != nil {
-			log.Println("Record topic update hatası:", err)
-		} else {
-			log.Printf("Kayıt (%s) konusu güncellendi: %s", sessionID, topic)
+// dispatch: Kanalın VAD segmentini sıra numarasıyla analize gönderir
+func (h *Handler) dispatch(s *liveSession, t *track, reason string, segment []byte, offsetSec float64) {
+	s.segmentSeq++
+	durationSec := float64(len(segment)) / float64(config.C.BytesPerSecond())
+	metrics.VADSegments.WithLabelValues(reason).Inc()
+	metrics.VADSegmentSeconds.Observe(durationSec)
+
+	segmentCopy := make([]byte, len(segment))
+	copy(segmentCopy, segment)
+
+	segCtx, segLog := logging.With(s.ctx, logging.KeySegment, s.segmentSeq)
+	segLog.Debug("vad segment dispatched", "reason", reason, "channel", t.channel, "offset_sec", offsetSec, "duration_sec", durationSec)
+
+	// Geri basınç: kuyruk doluysa okuma döngüsü yer açılana kadar bekler. Ses bağlantının
+	// tamponunda birikir ama kaybolmaz; istemci overloaded ile bilgilendirilir.
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.overloaded(segLog, "session", config.C.SessionQueueLimit)
+		s.slots <- struct{}{}
+	}
+
+	s.wg.Add(1)
+	s.reorder.add(s.segmentSeq)
+	s.publishLogged(s.log, msgQueue, map[string]interface{}{"depth": s.queueDepth.Add(1)})
+
+	seq, channel := s.segmentSeq, t.channel
+	job := func() { h.processAndRespond(segCtx, s, seq, channel, segmentCopy, offsetSec) }
+	if !h.pool.trySubmit(job) {
+		s.overloaded(segLog, "global", config.C.GlobalQueueLimit)
+		h.pool.submit(job)
+	}
+}
+
+// overloaded: Segment kuyruğa girmeden önce beklemek zorunda kaldı.
+// scope: "session" (oturumun kendi kuyruğu dolu) veya "global" (tüm oturumların worker kuyruğu dolu)
+func (s *liveSession) overloaded(log *slog.Logger, scope string, limit int) {
+	log.Warn("segment queue full, waiting", "scope", scope, "limit", limit, "queue_depth", s.queueDepth.Load())
+	metrics.Overloads.WithLabelValues(scope).Inc()
+	s.publishLogged(log, msgOverloaded, map[string]interface{}{
+		"scope":       scope,
+		"limit":       limit,
+		"queue_depth": s.queueDepth.Load(),
+	})
+}
+
+// flush: Kanalların tamponunda kalan son segmentleri başlangıç sırasıyla analize gönderir
+// (stop, duraklatma, kapanış veya kopan bağlantı)
+func (h *Handler) flush(s *liveSession, reason string) {
+	pending := slices.Clone(s.tracks)
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].segmentOffset() < pending[j].segmentOffset() })
+	for _, t := range pending {
+		if len(t.currentSegment) > 0 {
+			h.dispatch(s, t, reason, t.currentSegment, t.segmentOffset())
 		}
-	}()
+		t.resetSegment()
+	}
+}
+
+// offsetSec: Oturum zaman çizelgesinde işlenen sesin sonu (bütün kanallar birlikte ilerler)
+func (s *liveSession) offsetSec() float64 {
+	return float64(s.tracks[0].bytesProcessed) / float64(config.C.BytesPerSecond())
+}
+
+// sendStatus: Oturumun anlık durumu (durum değişikliklerinde gönderilir)
+func (s *liveSession) sendStatus(state string) {
+	s.publishLogged(s.log, msgStatus, map[string]interface{}{
+		"state":            state,
+		"offset_sec":       s.offsetSec(),
+		"bytes_received":   s.bytesReceived.Load(),
+		"segments_emitted": s.segmentsEmitted.Load(),
+		"queue_depth":      s.queueDepth.Load(),
+	})
+}
+
+// finishSession: Kalan sesi işler, segmentleri bekler ve konu analizini çalıştırır.
+// Sonuç session_complete ile (bağlıysa) istemciye ve izleyicilere bildirilir.
+func (h *Handler) finishSession(s *liveSession, flushReason string) {
+	defer s.unregister()
+	h.flush(s, flushReason)
+
+	// Tüm işlemlerin (Whisper/Sentiment vs) bitmesini bekle
+	s.wg.Wait()
+	s.reorder.stop()
+	s.log.Info("live session ended, starting topic analysis", "segments", s.segmentSeq, "offset_sec", s.offsetSec())
+
+	// --- KONU ANALİZİ (POST-PROCESSING) ---
+	topic, segmentCount := h.analyzeTopic(s.ctx, s.ID)
+	complete := map[string]interface{}{
+		"session_id": s.ID,
+		"segments":   segmentCount,
+	}
+	if topic != "" {
+		complete["topic"] = topic
+	}
+	s.publishLogged(s.log, msgSessionComplete, complete)
+	s.closeSpectators()
+}
+
+// analyzeTopic: Kaydın tüm segmentlerinin metnini konu analizine gönderir ve kaydı günceller.
+// Segment yoksa analiz yapılmaz ve boş konu döner.
+func (h *Handler) analyzeTopic(ctx context.Context, sessionID string) (topic string, segmentCount int) {
+	ctx, span := tracing.Start(ctx, "session.topic_analysis", trace.WithAttributes(
+		attribute.String(logging.KeyRecordID, sessionID),
+	))
+	defer span.End()
+	log := logging.From(ctx)
+
+	// 1. Bu kayıt için tüm segmentleri veritabanından çek
+	var segments []models.Segment
+	if err := database.DB.WithContext(ctx).Where("record_id = ?", sessionID).Order("start_offset asc").Find(&segments).Error; err != nil {
+		log.Error("segment query failed", "error", err)
+		return "", 0
+	}
+
+	if len(segments) == 0 {
+		return "", 0
+	}
+
+	// 2. Metinleri birleştir
+	var fullTextBuilder strings.Builder
+	for _, seg := range segments {
+		fullTextBuilder.WriteString(seg.Text)
+		fullTextBuilder.WriteString(" ")
+	}
+	fullText := strings.TrimSpace(fullTextBuilder.String())
+
+	// 3. Konu Analizi Servisini Çağır
+	topic, err := h.svc.TopicAnalyzer.AnalyzeTopic(ctx, fullText)
+	if err != nil {
+		log.Error("topic analysis failed, using fallback", logging.KeyService, "topic", "error_kind", services.KindOf(err), "error", err)
+		topic = "Belirsiz"
+		metrics.Fallbacks.WithLabelValues("topic", services.KindOf(err)).Inc()
+	}
+
+	// 4. Kaydı güncelle
+	if err := database.DB.WithContext(ctx).Model(&models.Record{}).Where("id = ?", sessionID).Update("topic", topic).Error; err != nil {
+		log.Error("record topic update failed", "error", err)
+	} else {
+		log.Info("record topic updated", "topic", topic)
+	}
+	return topic, len(segments)
 }
 
 // processAndRespond: Split (ayrılmış) mimariye göre güncellendi
-func processAndRespond(recordID string, pcmData []byte, offset float64, conn *websocket.Conn, mu *sync.Mutex, wg *sync.WaitGroup) {
-	defer wg.Done()
+// channel: Kanal bazlı ayrıştırmada segmentin kanalı (konuşmacı kanaldan belirlenir); 0: mono oturum
+func (h *Handler) processAndRespond(ctx context.Context, s *liveSession, seq, channel int, pcmData []byte, offset float64) {
+	defer s.wg.Done()
+	defer func() { <-s.slots }()
+	recordID := s.ID
+	defer func() {
+		s.publishLogged(logging.From(ctx), msgQueue, map[string]interface{}{"depth": s.queueDepth.Add(-1)})
+	}()
+
+	// Sonuçlar hemen gönderilmez; reorder buffer segment sırasına göre yayınlar.
+	// Hata durumunda da boş sonuçla tamamlanır ki sonraki segmentler beklemesin.
+	var results []outMessage
+	defer func() { s.reorder.complete(seq, results) }()
+	s.reorder.begin(seq)
+
+	// Her VAD segmenti ayrı bir trace: Whisper -> (Text, Audio) -> SQLite -> WebSocket
+	ctx, span := tracing.Start(ctx, "segment.process", trace.WithAttributes(
+		attribute.String(logging.KeyRecordID, recordID),
+		attribute.Float64("segment.offset_sec", offset),
+		attribute.Int("segment.bytes", len(pcmData)),
+		attribute.Int("segment.channel", channel),
+	))
+	defer span.End()
+	ctx, log := logging.With(ctx, "trace_id", span.SpanContext().TraceID().String())
+
+	metrics.SegmentsInFlight.Inc()
+	defer metrics.SegmentsInFlight.Dec()
+	defer func(start time.Time) {
+		metrics.SegmentProcessingSeconds.Observe(time.Since(start).Seconds())
+	}(time.Now())
 
 	// 1. Whisper Servisi: Sesi Metne Çevir
-	whisperResp, err := services.CallWhisperService(pcmData)
+	whisperResp, err := h.svc.Transcriber.Transcribe(ctx, pcmData, s.Settings.Language)
 	if err != nil {
-		log.Println("Whisper Error:", err)
+		// Reddedilen ses (bad_input) segmente özgüdür; diğer türler servis tarafı sorunlardır
+		level := slog.LevelError
+		if errors.Is(err, services.ErrBadInput) {
+			level = slog.LevelWarn
+		}
+		log.Log(ctx, level, "transcription failed, segment dropped", logging.KeyService, "whisper", "error_kind", services.KindOf(err), "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "transcription failed")
+		sendServiceError(s, log, "whisper", err, "segment_dropped")
 		return
 	}
+	span.SetAttributes(attribute.Int("segment.whisper_segments", len(whisperResp.Segments)))
 
-	for _, seg := range whisperResp.Segments {
-		// WAV oluştur (Audio servisi wav formatı bekler)
-		wavData := services.CreateWav(pcmData)
+	// WAV oluştur (Audio servisi wav formatı bekler); biçim sabit ve geçerli olduğu için hata dönmez.
+	// Kanal bazlı ayrıştırmada konuşmacı kanaldan bellidir; Audio servisi hiç çağrılmaz.
+	var wavData []byte
+	if channel == 0 {
+		wavData, _ = audio.EncodeWAV(audio.Format{SampleFormat: audio.PCM16, SampleRate: config.SampleRate, Channels: 1}, pcmData)
+	}
 
+	for _, seg := range whisperResp.Segments {
 		// 2. Text Service: Metin Duygu Analizi (Bağımsız Çağrı)
-		textSentiment, err := services.CallTextSentimentService(seg.Text)
+		textSentiment, err := h.svc.TextAnalyzer.AnalyzeSentiment(ctx, seg.Text)
 		if err != nil {
-			log.Printf("Text Sentiment Error (Text: %s): %v", seg.Text, err)
+			log.Warn("text sentiment failed, using fallback", logging.KeyService, "text_sentiment", "text", seg.Text, "error_kind", services.KindOf(err), "error", err)
 			textSentiment = "Nötr" // Hata durumunda varsayılan
+			metrics.Fallbacks.WithLabelValues("text_sentiment", services.KindOf(err)).Inc()
+			sendServiceError(s, log, "text_sentiment", err, "fallback")
 		}
 
-		// 3. Audio Service: Ses Duygusu ve Konuşmacı Tanıma (Bağımsız Çağrı)
-		audioPayload := models.ServicePayload{WavFile: wavData}
-		audioResp, err := services.CallAudioAnalyzeService(audioPayload)
-		if err != nil {
-			log.Println("Audio Analyze Error:", err)
-			// Hata durumunda varsayılan değerler
-			audioResp = models.ServicePayload{
-				VoiceSentiment:  "Bilinmiyor",
-				Speaker:         "Unknown",
-				SimilarityScore: 0.0,
+		var audioResp models.ServicePayload
+		var displaySpeaker string
+		var similarity float64
+		if channel > 0 {
+			// Kanal bazlı ayrıştırma: konuşmacı kanaldan bellidir, ses izi eşleşmesi (ve ses duygusu) yok
+			displaySpeaker = s.speakers[channel-1]
+		} else {
+			// 3. Audio Service: Ses Duygusu ve Konuşmacı Tanıma (Bağımsız Çağrı)
+			audioResp, err = h.svc.VoiceAnalyzer.AnalyzeVoice(ctx, wavData)
+			if err != nil {
+				log.Warn("audio analysis failed, using fallback", logging.KeyService, "audio", "error_kind", services.KindOf(err), "error", err)
+				// Hata durumunda varsayılan değerler
+				audioResp = models.ServicePayload{
+					VoiceSentiment:  "Bilinmiyor",
+					Speaker:         "Unknown",
+					SimilarityScore: 0.0,
+				}
+				metrics.Fallbacks.WithLabelValues("voice_analysis", services.KindOf(err)).Inc()
+				sendServiceError(s, log, "audio", err, "fallback")
 			}
-		}
 
-		// 4. Konuşmacı ID'sini İsim Soyisime Çevirme (Gateway'in görevi)
-		displaySpeaker := audioResp.Speaker // Varsayılan olarak ID kalsın (örn: "1")
-		if audioResp.Speaker != "Unknown" && audioResp.Speaker != "" {
-			var user models.User
-			// Veritabanında ID'ye göre kullanıcıyı ara
-			if result := database.DB.First(&user, "id = ?", audioResp.Speaker); result.Error == nil {
-				displaySpeaker = fmt.Sprintf("%s %s", user.Name, user.Surname)
+			// 4. Konuşmacı ID'sini İsim Soyisime Çevirme (Gateway'in görevi)
+			displaySpeaker = audioResp.Speaker // Varsayılan olarak ID kalsın (örn: "1")
+			similarity = audioResp.SimilarityScore
+			if audioResp.Speaker != "Unknown" && audioResp.Speaker != "" {
+				var user models.User
+				// Veritabanında ID'ye göre kullanıcıyı ara
+				if result := database.DB.WithContext(ctx).First(&user, "id = ?", audioResp.Speaker); result.Error == nil {
+					displaySpeaker = fmt.Sprintf("%s %s", user.Name, user.Surname)
+				}
 			}
 		}
 
@@ -211,14 +643,17 @@ func processAndRespond(recordID string, pcmData []byte, offset float64, conn *we
 			Text:            seg.Text,
 			TextSentiment:   textSentiment,            // Text servisinden geldi
 			VoiceSentiment:  audioResp.VoiceSentiment, // Audio servisinden geldi
-			Speaker:         displaySpeaker,           // DB'den çözüldü
-			SimilarityScore: audioResp.SimilarityScore,
+			Speaker:         displaySpeaker,           // DB'den (veya kanaldan) çözüldü
+			SimilarityScore: similarity,
+			Channel:         channel,
+		}
+		if err := database.DB.WithContext(ctx).Create(&newSegment).Error; err != nil {
+			log.Error("segment insert failed", "error", err)
 		}
-		database.DB.Create(&newSegment)
 
-		// Frontend'e yanıt gönder
-		response := map[string]interface{}{
-			"type": "live_analysis",
+		// Frontend'e gönderilecek sonuç (sırası gelince)
+		results = append(results, outMessage{msgType: msgLiveAnalysis, fields: map[string]interface{}{
+			"seq": seq,
 			"payload": models.LiveAnalysisResult{
 				Start:           finalStart,
 				End:             finalEnd,
@@ -226,14 +661,38 @@ func processAndRespond(recordID string, pcmData []byte, offset float64, conn *we
 				TextSentiment:   textSentiment,
 				VoiceSentiment:  audioResp.VoiceSentiment,
 				Speaker:         displaySpeaker,
-				SimilarityScore: audioResp.SimilarityScore,
+				SimilarityScore: similarity,
+				Channel:         channel,
 			},
-		}
+		}})
+	}
+}
 
-		mu.Lock()
-		if err := conn.WriteJSON(response); err != nil {
-			log.Println("WS Write Error:", err)
+// emitResults: Sırası gelen segmentin live_analysis mesajlarını yayınlar
+func (s *liveSession) emitResults(msgs []outMessage, late bool) {
+	for _, msg := range msgs {
+		if late {
+			msg.fields["late"] = true
+		}
+		err := s.publish(msg.msgType, msg.fields)
+		if errors.Is(err, errDetached) || errors.Is(err, errConnClosed) {
+			s.log.Debug("client detached, result kept in database only")
+		} else if err != nil {
+			s.log.Warn("websocket write failed", "error", err)
+		} else {
+			metrics.LiveResults.Inc()
+			s.segmentsEmitted.Add(1)
 		}
-		mu.Unlock()
 	}
 }
+
+// sendServiceError: Servis hatasını istemciye bildirir.
+// action: "segment_dropped" (segment analiz edilemedi) veya "fallback" (varsayılan değer kullanıldı)
+func sendServiceError(s *liveSession, log *slog.Logger, service string, err error, action string) {
+	s.publishLogged(log, msgServiceError, map[string]interface{}{
+		"service": service,
+		"kind":    services.KindOf(err),
+		"action":  action,
+		"message": err.Error(),
+	})
+}
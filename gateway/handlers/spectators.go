@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"gateway/logging"
+	"gateway/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// spectatorBuffer: Yavaş bir izleyici için biriktirilen en fazla mesaj; dolarsa izleyici düşürülür
+// ki oturumun kendisi (ve diğer izleyiciler) beklemesin.
+const spectatorBuffer = 256
+
+// historyLimit: Oturum başına saklanan en fazla live_analysis mesajı. Geç katılan izleyici son sonuçları,
+// devam eden istemci bağlantı koptuğundan beri kaçırdıklarını alır; ikisi için de tüm oturum gerekmez.
+const historyLimit = 200
+
+// outMessage: Kuyruğa alınmış sunucu mesajı
+type outMessage struct {
+	msgType string
+	fields  map[string]interface{}
+}
+
+// spectator: Oturumu salt okunur izleyen bağlantı
+type spectator struct {
+	lc  *liveConn
+	out chan outMessage
+}
+
+// publish: Mesajı oturumun istemcisine ve tüm izleyicilere gönderir; istemciye yazma hatasını döner.
+// live_analysis mesajları sonradan katılan izleyiciler ve devam ettirilen istemci için geçmişe eklenir.
+// s.mu altında yapılır ki devam ettirmedeki tekrar gönderimle araya girmesin (send beklemez).
+func (s *liveSession) publish(msgType string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broadcast(msgType, fields)
+	err := s.send(msgType, fields)
+	if msgType == msgLiveAnalysis && err == nil {
+		s.delivered = len(s.history)
+	}
+	return err
+}
+
+func (s *liveSession) publishLogged(log *slog.Logger, msgType string, fields map[string]interface{}) {
+	logSendError(log, msgType, s.publish(msgType, fields))
+}
+
+// broadcast: s.mu tutulurken çağrılır
+func (s *liveSession) broadcast(msgType string, fields map[string]interface{}) {
+	msg := outMessage{msgType: msgType, fields: fields}
+	if msgType == msgLiveAnalysis {
+		s.appendHistory(msg)
+	}
+	for sp := range s.spectators {
+		select {
+		case sp.out <- msg:
+		default:
+			sp.lc.logger().Warn("spectator too slow, dropped")
+			s.dropSpectator(sp)
+		}
+	}
+}
+
+// appendHistory: En eski mesajlar historyLimit aşılınca atılır; delivered aynı mesajı göstermeye devam eder.
+// s.mu tutulurken çağrılır.
+func (s *liveSession) appendHistory(msg outMessage) {
+	if len(s.history) == historyLimit {
+		// Yeni diziye kopyalanır ki atılan mesajlar alttaki dizide tutulmasın
+		s.history = append(make([]outMessage, 0, historyLimit), s.history[1:]...)
+		s.delivered = max(s.delivered-1, 0)
+	}
+	s.history = append(s.history, msg)
+}
+
+// subscribe: İzleyiciyi kaydeder; saklanan live_analysis geçmişi kuyruğun başına konur.
+// Oturum bitmişse nil döner.
+func (s *liveSession) subscribe(lc *liveConn) (*spectator, []outMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return nil, nil
+	}
+	sp := &spectator{lc: lc, out: make(chan outMessage, spectatorBuffer)}
+	s.spectators[sp] = struct{}{}
+	metrics.Spectators.Inc()
+	return sp, append([]outMessage(nil), s.history...)
+}
+
+func (s *liveSession) unsubscribe(sp *spectator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropSpectator(sp)
+}
+
+// dropSpectator: s.mu tutulurken çağrılır; kanal sadece bir kez kapanır
+func (s *liveSession) dropSpectator(sp *spectator) {
+	if _, ok := s.spectators[sp]; !ok {
+		return
+	}
+	delete(s.spectators, sp)
+	close(sp.out)
+	metrics.Spectators.Dec()
+}
+
+// closeSpectators: Oturum bitti (session_complete gönderildikten sonra); izleyici bağlantıları kapanır
+func (s *liveSession) closeSpectators() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = true
+	for sp := range s.spectators {
+		s.dropSpectator(sp)
+	}
+}
+
+// lookupSession: Bağlı veya devam ettirilmeyi bekleyen oturum
+func lookupSession(id string) *liveSession {
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.sessions[id]
+}
+
+// GET /ws/sessions/{id}/watch?token=<watch_token>
+// Aktif bir oturumun analiz sonuçlarını ve durum olaylarını salt okunur olarak yayınlar.
+// Sonradan katılan izleyici önce o ana kadar üretilen segmentleri alır. Anahtar, oturumu açan istemciye
+// session_started ile verilir; tarayıcılar WebSocket isteğine başlık ekleyemediği için sorgu parametresidir.
+func (h *Handler) HandleWatchSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if isDraining() {
+		http.Error(w, "Sunucu kapanıyor", http.StatusServiceUnavailable)
+		return
+	}
+	sess := lookupSession(id)
+	if sess == nil {
+		http.Error(w, "Aktif oturum bulunamadı", http.StatusNotFound)
+		return
+	}
+	if !validToken(sess.watchToken, r.URL.Query().Get("token")) {
+		sess.log.Warn("spectator rejected, invalid watch token", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Geçersiz izleme anahtarı", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		sess.log.Warn("websocket upgrade failed", "remote_addr", r.RemoteAddr, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	lc := newLiveConn(conn)
+	defer lc.close(0, "")
+	if !beginSession(lc) {
+		lc.close(websocket.CloseTryAgainLater, "Sunucu kapanıyor")
+		return
+	}
+	defer endSession(lc)
+
+	_, log := logging.With(sess.ctx, "spectator", r.RemoteAddr)
+	lc.setLogger(log)
+	sp, history := sess.subscribe(lc)
+	if sp == nil {
+		reject(lc, "session_finished", errSessionFinished, "Oturum tamamlandı")
+		return
+	}
+	log.Info("spectator joined", "history_segments", len(history))
+
+	// Salt okunur: istemciden gelen mesajlar yok sayılır, okuma sadece kapanışı fark etmek için
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				sess.unsubscribe(sp)
+				return
+			}
+		}
+	}()
+
+	// Geçmiş yazma kuyruğundan uzun olabilir; bu goroutine sadece bu izleyiciye yazdığı için beklenir
+	backlog := append([]outMessage{{msgType: msgWatchStarted, fields: map[string]interface{}{
+		"session_id":       sess.ID,
+		"started_at":       sess.StartedAt,
+		"settings":         sess.Settings,
+		"history_segments": len(history),
+	}}}, history...)
+	forward := func(msg outMessage) bool {
+		if err := lc.sendWait(msg.msgType, msg.fields); err != nil {
+			log.Info("spectator write failed", "error", err)
+			sess.unsubscribe(sp)
+			return false
+		}
+		return true
+	}
+	for _, msg := range backlog {
+		if !forward(msg) {
+			return
+		}
+	}
+	for msg := range sp.out {
+		if !forward(msg) {
+			return
+		}
+	}
+
+	log.Info("spectator left")
+	lc.close(websocket.CloseNormalClosure, "Oturum tamamlandı")
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import "testing"
+
+// Geçmiş uzun oturumda sınırsız büyümemeli; kırpılınca delivered aynı mesajı göstermeye devam etmeli
+func TestHistoryCapped(t *testing.T) {
+	s := &liveSession{spectators: make(map[*spectator]struct{})}
+	analysis := func(seq int) map[string]interface{} { return map[string]interface{}{"seq": seq} }
+
+	for seq := 1; seq <= historyLimit; seq++ {
+		s.broadcast(msgLiveAnalysis, analysis(seq))
+	}
+	s.delivered = historyLimit - 5 // Son 5 sonuç istemcinin kuyruğuna verilemedi
+
+	const extra = 30
+	for seq := historyLimit + 1; seq <= historyLimit+extra; seq++ {
+		s.broadcast(msgLiveAnalysis, analysis(seq))
+	}
+	s.broadcast(msgStatus, map[string]interface{}{"status": "paused"})
+
+	if len(s.history) != historyLimit {
+		t.Fatalf("history holds %d messages, want %d", len(s.history), historyLimit)
+	}
+	if first := s.history[0].fields["seq"]; first != extra+1 {
+		t.Errorf("oldest kept seq = %v, want %d", first, extra+1)
+	}
+	missed := s.missedResults(nil)
+	if len(missed) != 5+extra || missed[0].fields["seq"] != historyLimit-4 {
+		t.Errorf("missed %d results starting at seq %v, want %d starting at %d",
+			len(missed), missed[0].fields["seq"], 5+extra, historyLimit-4)
+	}
+	// Kırpılan bir seq'ten devam eden istemci saklanan her şeyi alır
+	lastSeq := 3
+	if got := s.missedResults(&lastSeq); len(got) != historyLimit {
+		t.Errorf("resume after trimmed seq replays %d results, want %d", len(got), historyLimit)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gateway/audio"
+	"gateway/config"
+)
+
+// POST /api/records/upload
+// Kaydedilmiş bir ses dosyasını canlı oturumla aynı hattan (VAD, worker havuzu, servisler) analiz eder.
+// Form alanları: "file" (WAV veya ffmpeg'in çözdüğü biçimler) ve isteğe bağlı "settings" (start
+// mesajının ayar alanları; sample_rate ve encoding dosyadan gelir). diarization "channel" ise her kanal
+// kendi VAD'iyle segmentlenir. Analiz arka planda sürer; yanıt oturum kimliği ve izleme anahtarıyla hemen
+// döner, ilerleme /ws/sessions/{id}/watch ile izlenebilir. Aynı anda max_concurrent_recordings kayıt
+// işlenir; sınır doluysa 429 döner.
+func (h *Handler) HandleUploadRecord(w http.ResponseWriter, r *http.Request) {
+	if isDraining() {
+		http.Error(w, "Sunucu kapanıyor", http.StatusServiceUnavailable)
+		return
+	}
+	// Yer, form okunmadan alınır ve analiz bitince bırakılır
+	select {
+	case h.recordings <- struct{}{}:
+	default:
+		http.Error(w, "Çok fazla kayıt analiz ediliyor, daha sonra tekrar deneyin", http.StatusTooManyRequests)
+		return
+	}
+	accepted := false
+	defer func() {
+		if !accepted {
+			<-h.recordings
+		}
+	}()
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(config.C.MaxRecordingBytes))
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Dosya çok büyük (en fazla %d byte)", config.C.MaxRecordingBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Form okunamadı", 400)
+		return
+	}
+
+	var msg settingsMessage
+	if raw := r.FormValue("settings"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			http.Error(w, "settings geçersiz: "+err.Error(), 400)
+			return
+		}
+	}
+	settings := msg.settings()
+	// Dosya sunucunun hızında ham PCM'e çözülür; kanal bazlı ayrıştırma yoksa mono'ya indirilir
+	settings.Encoding, settings.SampleRate = encodingPCM16, config.SampleRate
+	if settings.Diarization != diarizationChannel {
+		settings.Channels = 1
+	}
+	if err := settings.validate(); err != nil {
+		http.Error(w, "settings geçersiz: "+err.Error(), 400)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Dosya alınamadı", 400)
+		return
+	}
+	defer file.Close()
+
+	opts := audio.DecodeOptions{
+		SampleRate: config.SampleRate,
+		MaxBytes:   int64(config.C.DurationBytes(config.C.MaxRecordingAudio.Duration)),
+	}
+	if settings.Diarization == diarizationChannel {
+		opts.Channels = settings.Channels
+	}
+	decodeCtx, cancel := context.WithTimeout(r.Context(), config.C.DecodeTimeout.Duration)
+	defer cancel()
+	pcm, format, err := h.decoders.Decode(decodeCtx, file, opts)
+	if err == nil && len(pcm) == 0 {
+		err = errors.New("dosyada ses yok")
+	}
+	if err != nil {
+		slog.Warn("recording upload decode failed", "format", format, "error", err)
+		http.Error(w, "Ses dosyası çözülemedi: "+err.Error(), decodeStatus(err))
+		return
+	}
+	// Mono'da kopya alınmaz; bellekte kaydın tek bir kopyası kalır
+	channels := [][]byte{pcm}
+	if settings.Channels > 1 {
+		channels, _ = audio.SplitChannels(pcm, settings.Channels)
+	}
+
+	sess := newRecordSession(settings, true, r.RemoteAddr)
+	sess.upload = true
+	durationSec := float64(len(channels[0])) / float64(config.C.BytesPerSecond())
+	accepted = startBackground(func() {
+		defer func() { <-h.recordings }()
+		h.analyzeUpload(sess, channels)
+	})
+	if !accepted {
+		// Bu arada kapanış başladı; kayıt boş kalır
+		sess.unregister()
+		http.Error(w, "Sunucu kapanıyor", http.StatusServiceUnavailable)
+		return
+	}
+	sess.log.Info("recording upload accepted", "format", format, "channels", settings.Channels, "duration_sec", durationSec)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "accepted",
+		"session_id":   sess.ID,
+		"watch_token":  sess.watchToken,
+		"format":       format,
+		"channels":     settings.Channels,
+		"duration_sec": durationSec,
+	})
+}
+
+// uploadChunk: Yüklenen kayıtta kanalların birlikte ilerlediği adım; segment sıra numaraları
+// kanallar arasında da yaklaşık zaman sırasında kalır
+const uploadChunk = time.Second
+
+// analyzeUpload: Kanalların PCM'ini oturumun track'lerine canlı akış gibi parça parça verir,
+// ardından oturumu stop ile bitmiş gibi tamamlar (kalan segmentler, konu analizi). Sunucu kapanırsa
+// canlı oturumlar gibi o ana kadarki sesle tamamlanır.
+func (h *Handler) analyzeUpload(s *liveSession, channels [][]byte) {
+	step := config.C.DurationBytes(uploadChunk)
+	for off := 0; off < len(channels[0]); off += step {
+		if isDraining() {
+			s.log.Warn("server shutting down, recording upload analysed partially", "offset_sec", s.offsetSec())
+			h.finishSession(s, "shutdown")
+			return
+		}
+		end := min(off+step, len(channels[0]))
+		for i, t := range s.tracks {
+			h.processAudio(s, t, channels[i][off:end])
+		}
+		s.bytesReceived.Add(int64((end - off) * len(channels)))
+	}
+	h.finishSession(s, "stop")
+}
@@ -0,0 +1,63 @@
+// Package logging: Gateway'in yapılandırılmış (slog) log kurulumu ve context'e bağlı logger'lar.
+// Log mesajları İngilizce, sabit metinlerdir; değişen değerler alan olarak eklenir. Yorumlar ve
+// istemciye dönen hata mesajları Türkçedir.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Ortak alan adları: bir oturuma ait tüm satırlar bu anahtarlarla filtrelenebilir
+const (
+	KeyRecordID = "record_id"
+	KeySegment  = "segment_seq"
+	KeyService  = "service"
+)
+
+// Setup: Varsayılan slog logger'ını kurar. Standart "log" paketi de bu handler'a yönlenir.
+// level: debug | info | warn | error, format: json | text
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log seviyesi %q geçersiz: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("log formatı %q geçersiz (json, text)", format)
+	}
+
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+type ctxKey struct{}
+
+// WithLogger: Logger'ı context'e ekler; alt fonksiyonlar From ile aynı alanları taşıyan logger'ı alır
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// From: Context'teki logger'ı döner, yoksa varsayılanı
+func From(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With: Context'teki logger'a alan ekleyip yeni context döner
+func With(ctx context.Context, args ...any) (context.Context, *slog.Logger) {
+	l := From(ctx).With(args...)
+	return WithLogger(ctx, l), l
+}
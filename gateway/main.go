@@ -1,29 +1,88 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"gateway/audio"
+	"gateway/config"
 	"gateway/database"
 	"gateway/handlers"
-	"gateway/models"
+	"gateway/logging"
+	"gateway/services"
+	"gateway/tracing"
 )
 
 func main() {
+	// 0. Yapılandırmayı Yükle (dosya + ortam değişkenleri + bayraklar) ve log'u kur
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := logging.Setup(os.Stdout, cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("effective configuration", "config", cfg)
+
+	// ffmpeg olmadan sunucu çalışır ama sadece WAV kayıtları ve ham PCM akışları kabul edilir
+	if err := audio.FFmpegAvailable(cfg.FFmpegPath); err != nil {
+		slog.Warn("ffmpeg not installed: compressed enrollment uploads and webm_opus/ogg_opus streams are rejected", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, cfg.TraceEndpoint, cfg.TraceSampleRatio)
+	if err != nil {
+		slog.Error("tracing setup failed", "error", err)
+		os.Exit(1)
+	}
+
 	// 1. Veritabanını Başlat (GORM)
 	database.Init()
 
-	// 2. Rotaları Tanımla
-	// WebSocket
-	http.HandleFunc("/ws", handlers.HandleLiveAudio)
+	// Python servis istemcileri (handler'lara arayüz olarak verilir)
+	h := handlers.New(handlers.NewHTTPServices(services.NewHTTPClient(cfg)))
 
-	// REST API
-	http.HandleFunc("/api/users", handlers.HandleGetUsers)
-	http.HandleFunc("/api/record_user", handlers.HandleRecordUser)
-	http.HandleFunc("/api/records", handlers.HandleGetRecords)
-	http.HandleFunc("/api/segments", handlers.HandleGetSegments)
+	// 2. Rotaları Tanımla
+	mux := h.Routes()
 
 	// 3. Sunucuyu Başlat
-	log.Printf("Gateway başlatıldı: %s", models.Port)
-	log.Fatal(http.ListenAndServe(models.Port, nil))
+	srv := &http.Server{Addr: cfg.Port, Handler: mux}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Info("gateway started", "addr", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	// 4. Kapanış: yeni bağlantıları durdur, canlı oturumları boşalt, DB'yi kapat
+	slog.Info("shutdown signal received", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http server shutdown failed", "error", err)
+	}
+	if err := handlers.Shutdown(shutdownCtx); err != nil {
+		slog.Error("live sessions not drained before deadline", "error", err)
+	}
+	if err := database.Close(); err != nil {
+		slog.Error("database close failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("tracing flush failed", "error", err)
+	}
+	slog.Info("gateway stopped")
 }
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// --- Canlı Oturum (WebSocket) ---
+
+var SessionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "gateway_ws_sessions_active",
+	Help: "Açık /ws canlı analiz oturumu sayısı.",
+})
+
+var Spectators = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "gateway_ws_spectators",
+	Help: "Canlı oturumları izleyen salt okunur bağlantı sayısı.",
+})
+
+var SessionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "gateway_ws_sessions_total",
+	Help: "Başlatılan toplam /ws oturumu.",
+})
+
+// reason: "silence" (VAD sessizlik), "max_length" (max_segment'e ulaşan konuşma bölündü),
+// "pause" (istemci duraklattı), "stop" (istemci STOP veya yüklenen kaydın sonu),
+// "disconnect" (bağlantı koptu, devam ettirilmedi), "shutdown" (sunucu kapanışı)
+var VADSegments = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_vad_segments_total",
+	Help: "VAD tarafından kesilip analize gönderilen segment sayısı.",
+}, []string{"reason"})
+
+var VADSegmentSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "gateway_vad_segment_duration_seconds",
+	Help:    "Analize gönderilen VAD segmentlerinin ses süresi.",
+	Buckets: []float64{1, 2, 3, 5, 8, 13, 21, 34, 60, 120},
+})
+
+// --- Segment İşleme (processAndRespond) ---
+
+var SegmentsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "gateway_segments_in_flight",
+	Help: "Şu an işlenmekte olan VAD segmenti sayısı.",
+})
+
+var SegmentQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "gateway_segment_queue_depth",
+	Help: "Tüm oturumlarda boş worker bekleyen VAD segmenti sayısı.",
+})
+
+var SegmentQueueWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "gateway_segment_queue_wait_seconds",
+	Help:    "Bir VAD segmentinin analize başlamadan önce kuyrukta beklediği süre.",
+	Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+})
+
+// scope: "session" (oturum kuyruğu dolu) veya "global" (worker kuyruğu dolu)
+var Overloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_overloaded_total",
+	Help: "Kuyruk dolu olduğu için okuma döngüsünün beklemek zorunda kaldığı segment sayısı.",
+}, []string{"scope"})
+
+var SegmentProcessingSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "gateway_segment_processing_seconds",
+	Help:    "Bir VAD segmentinin Whisper'dan WebSocket yanıtına kadar toplam işlenme süresi.",
+	Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+})
+
+var LiveResults = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "gateway_live_results_total",
+	Help: "İstemciye gönderilen live_analysis mesajı sayısı.",
+})
+
+// result: "sent", "stale" (nihai sonuç önce yayınlandı), "skipped" (önceki ara istek sürüyor veya kuyruk dolu), "error"
+var InterimTranscripts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_interim_transcripts_total",
+	Help: "Süren konuşma için istenen ara transkripsiyon sayısı.",
+}, []string{"result"})
+
+var StreamDecodeFailures = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "gateway_stream_decode_failures_total",
+	Help: "Sıkıştırılmış ses akışı çözülemediği için sonlandırılan oturum sayısı.",
+})
+
+var ReorderTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "gateway_reorder_timeouts_total",
+	Help: "Sonucu reorder_timeout içinde gelmediği için sırası atlanan segment sayısı.",
+})
+
+// field: "text_sentiment" (Nötr), "voice_analysis" (Bilinmiyor/Unknown), "topic" (Belirsiz)
+var Fallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_fallbacks_total",
+	Help: "Servis hatası nedeniyle varsayılan değere düşülen sonuç sayısı.",
+}, []string{"field", "kind"})
+
+// --- Python Servis Çağrıları ---
+
+var serviceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gateway_service_request_duration_seconds",
+	Help:    "Python servislerine yapılan isteklerin süresi.",
+	Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+}, []string{"service", "result"})
+
+var serviceRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_service_requests_total",
+	Help: "Python servislerine yapılan istek sayısı.",
+}, []string{"service", "result"})
+
+var ServiceRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_service_retries_total",
+	Help: "Python servislerine yapılan tekrar deneme sayısı.",
+}, []string{"service"})
+
+// 0: closed, 1: half_open, 2: open
+var BreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gateway_circuit_breaker_state",
+	Help: "Servis başına devre kesici durumu (0 closed, 1 half_open, 2 open).",
+}, []string{"service"})
+
+// ObserveService: Servis çağrısının süresini ve sonucunu işler.
+// result: "success" veya hata türü ("unavailable", "bad_input", "model_error", "decode_error")
+func ObserveService(service string, start time.Time, result string) {
+	serviceDuration.WithLabelValues(service, result).Observe(time.Since(start).Seconds())
+	serviceRequests.WithLabelValues(service, result).Inc()
+}
@@ -2,15 +2,6 @@ package models
 
 import "time"
 
-// Settings
-const (
-	Port            = ":8080"
-	SampleRate      = 16000
-	PacketSize      = 640
-	MinSegmentBytes = SampleRate * 2 * 3
-	DBName          = "db.sqlite"
-)
-
 // --- Veritabanı Modelleri (GORM) ---
 
 type User struct {
@@ -27,6 +18,16 @@ type Record struct {
 	Topic     string    `gorm:"default:'Genel'" json:"topic"`
 	Sentiment string    `gorm:"default:'Nötr'" json:"-"`
 
+	// Oturum ayarları (istemcinin "start" mesajından; eski istemcilerde varsayılanlar)
+	Title                string `json:"title"`
+	Language             string `json:"language"`
+	SampleRate           int    `json:"sample_rate"`
+	Channels             int    `json:"channels"`
+	Diarization          string `json:"diarization"` // "channel": konuşmacılar kanaldan
+	Encoding             string `json:"encoding"`
+	VADAggressiveness    int    `json:"vad_aggressiveness"`
+	ExpectedParticipants int    `json:"expected_participants"` // 0: bilinmiyor
+
 	// İlişkiler (DB'de foreign key)
 	Segments []Segment `gorm:"foreignKey:RecordID" json:"-"`
 
@@ -44,7 +45,8 @@ type Segment struct {
 	TextSentiment   string  `json:"textSentiment"`
 	VoiceSentiment  string  `json:"voiceSentiment"`
 	Speaker         string  `json:"speaker"`
-	SimilarityScore float64 `json:"similarity_score"` // YENİ: Veritabanına kaydetmek için eklendi
+	SimilarityScore float64 `json:"similarity_score"`  // YENİ: Veritabanına kaydetmek için eklendi
+	Channel         int     `json:"channel,omitempty"` // Kanal bazlı ayrıştırmada 1'den başlayan kanal
 }
 
 // --- DTO (Data Transfer Objects) ---
@@ -58,6 +60,14 @@ type LiveAnalysisResult struct {
 	VoiceSentiment  string  `json:"voiceSentiment"`
 	Speaker         string  `json:"speaker"`
 	SimilarityScore float64 `json:"similarity_score"` // YENİ: Frontend'e göndermek için eklendi
+	Channel         int     `json:"channel,omitempty"`
+}
+
+// InterimResult: Süren konuşmanın geçici transkripsiyonu (aynı seq'in live_analysis'i ile değiştirilir)
+type InterimResult struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
 }
 
 // Servisler arası iletişim payload'ı
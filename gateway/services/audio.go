@@ -1,118 +1,156 @@
 package services
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os/exec"
+	"net/url"
 	"time"
 
+	"gateway/config"
+	"gateway/metrics"
 	"gateway/models"
 )
 
-const (
-	WhisperServiceURL = "http://localhost:5000/"
-	AudioServiceURL   = "http://localhost:5001/" // Audio Service (Ses İşleme)
-	TextServiceURL    = "http://localhost:5002/" // Text Service (Metin İşleme)
-)
+// HTTPClient: Python servislerine HTTP üzerinden bağlanan varsayılan gerçekleme.
+// Transcriber, VoiceAnalyzer, TextAnalyzer, TopicAnalyzer ve Enroller arayüzlerini sağlar; servis başına
+// Pinger'lar Pingers ile alınır.
+type HTTPClient struct {
+	client  *http.Client
+	whisper *downstream
+	audio   *downstream // Audio Service (Ses İşleme)
+	text    *downstream // Text Service (Metin İşleme)
+}
+
+// NewHTTPClient: Adresleri, zaman aşımını ve tekrar deneme / devre kesici ayarlarını yapılandırmadan alır
+func NewHTTPClient(cfg *config.Config) *HTTPClient {
+	return &HTTPClient{
+		client:  &http.Client{Timeout: cfg.ServiceTimeout.Duration},
+		whisper: newDownstream("whisper", cfg.WhisperServiceURL, cfg.ServicePolicies.Whisper),
+		audio:   newDownstream("audio", cfg.AudioServiceURL, cfg.ServicePolicies.Audio),
+		text:    newDownstream("text", cfg.TextServiceURL, cfg.ServicePolicies.Text),
+	}
+}
 
-var httpClient = &http.Client{Timeout: 60 * time.Second}
+// Transcribe: Sesi metne çevirmek için (Whisper)
+func (c *HTTPClient) Transcribe(ctx context.Context, pcmData []byte, language string) (_ models.ServicePayload, err error) {
+	defer observe("whisper", time.Now(), &err)
 
-// CallWhisperService: Sesi metne çevirmek için (Whisper)
-func CallWhisperService(pcmData []byte) (models.ServicePayload, error) {
-	resp, err := httpClient.Post(WhisperServiceURL, "application/octet-stream", bytes.NewReader(pcmData))
+	// Dil, gövde ham PCM olduğu için sorgu parametresiyle iletilir
+	var path string
+	if language != "" {
+		path = "?language=" + url.QueryEscape(language)
+	}
+
+	resp, err := c.post(ctx, call{ds: c.whisper, service: "whisper", path: path, contentType: "application/octet-stream", body: pcmData, idempotent: true})
 	if err != nil {
 		return models.ServicePayload{}, err
 	}
 	defer resp.Body.Close()
 
 	var result models.ServicePayload
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	return result, err
+	if err := decodeResponse("whisper", resp, &result); err != nil {
+		return models.ServicePayload{}, err
+	}
+	return result, nil
 }
 
-// CallAudioAnalyzeService: Sadece ses analizi (Voice Sentiment + Speaker Identification)
-func CallAudioAnalyzeService(payload models.ServicePayload) (models.ServicePayload, error) {
+// AnalyzeVoice: Sadece ses analizi (Voice Sentiment + Speaker Identification)
+func (c *HTTPClient) AnalyzeVoice(ctx context.Context, wavData []byte) (_ models.ServicePayload, err error) {
+	defer observe("audio", time.Now(), &err)
+
 	// Python servisi "wav_file" alanını bekliyor
 	requestBody := map[string]interface{}{
-		"wav_file": payload.WavFile,
+		"wav_file": wavData,
 	}
 	jsonData, _ := json.Marshal(requestBody)
 
 	// Endpoint: /analyze_audio
-	resp, err := httpClient.Post(AudioServiceURL+"analyze_audio", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.post(ctx, call{ds: c.audio, service: "audio", path: "analyze_audio", contentType: "application/json", body: jsonData, idempotent: true})
 	if err != nil {
 		return models.ServicePayload{}, err
 	}
 	defer resp.Body.Close()
 
-	// Gelen yanıtı parse et
-	var responseMap map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
+	var result models.ServicePayload
+	if err := decodeResponse("audio", resp, &result); err != nil {
 		return models.ServicePayload{}, err
 	}
-
-	// Güvenli tip dönüşümü (Type Assertion)
-	voiceSent, _ := responseMap["voice_sentiment"].(string)
-	speaker, _ := responseMap["speaker"].(string)
-	simScore, _ := responseMap["similarity_score"].(float64)
-
-	result := models.ServicePayload{
-		VoiceSentiment:  voiceSent,
-		Speaker:         speaker,
-		SimilarityScore: simScore,
+	// Boş konuşmacı sessizce "başarılı" sayılmasın
+	if result.Speaker == "" {
+		return models.ServicePayload{}, missingField("audio", "speaker")
 	}
-	return result, nil
+	return models.ServicePayload{
+		VoiceSentiment:  result.VoiceSentiment,
+		Speaker:         result.Speaker,
+		SimilarityScore: result.SimilarityScore,
+	}, nil
 }
 
-// CallTextSentimentService: Sadece metin duygu analizi
-func CallTextSentimentService(text string) (string, error) {
+// AnalyzeSentiment: Sadece metin duygu analizi
+func (c *HTTPClient) AnalyzeSentiment(ctx context.Context, text string) (_ string, err error) {
+	defer observe("text_sentiment", time.Now(), &err)
+
 	requestBody := map[string]string{"text": text}
 	jsonData, _ := json.Marshal(requestBody)
 
 	// Endpoint: /sentiment
-	resp, err := httpClient.Post(TextServiceURL+"sentiment", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.post(ctx, call{ds: c.text, service: "text_sentiment", path: "sentiment", contentType: "application/json", body: jsonData, idempotent: true})
 	if err != nil {
-		return "Hata", err
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	var responseMap map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
-		return "Hata", err
+	var result struct {
+		Sentiment string `json:"sentiment"`
 	}
-
-	sentiment, _ := responseMap["sentiment"].(string)
-	return sentiment, nil
+	if err := decodeResponse("text_sentiment", resp, &result); err != nil {
+		return "", err
+	}
+	switch result.Sentiment {
+	case "":
+		return "", missingField("text_sentiment", "sentiment")
+	case "Hata":
+		// Text servisi model hatasını 200 + "Hata" olarak döner
+		return "", &ServiceError{Service: "text_sentiment", Kind: ErrModelError, Status: resp.StatusCode, Message: "duygu modeli hata döndü"}
+	}
+	return result.Sentiment, nil
 }
 
-// CallTopicAnalysisService: Konuşmanın tamamı için konu analizi (Chatbot/LLM)
-func CallTopicAnalysisService(fullText string) (string, error) {
+// AnalyzeTopic: Konuşmanın tamamı için konu analizi (Chatbot/LLM)
+func (c *HTTPClient) AnalyzeTopic(ctx context.Context, fullText string) (_ string, err error) {
+	defer observe("topic", time.Now(), &err)
+
 	requestBody := map[string]string{"text": fullText}
 	jsonData, _ := json.Marshal(requestBody)
 
 	// Endpoint: /analyze_topic
 	// TextServiceURL (5002) üzerinden hizmet veriyoruz
-	resp, err := httpClient.Post(TextServiceURL+"analyze_topic", "application/json", bytes.NewBuffer(jsonData))
+	// Yavaş LLM çağrısı oturumun tamamlanmasını bekletir; tekrar denemek bu süreyi max_attempts katına
+	// çıkarır. Hata durumunda zaten "Belirsiz" konusuna düşülür, bu yüzden tekrar denenmez.
+	resp, err := c.post(ctx, call{ds: c.text, service: "topic", path: "analyze_topic", contentType: "application/json", body: jsonData})
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	var responseMap map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
+	var result struct {
+		Topic string `json:"topic"`
+	}
+	if err := decodeResponse("topic", resp, &result); err != nil {
 		return "", err
 	}
-
-	topic, _ := responseMap["topic"].(string)
-	return topic, nil
+	if result.Topic == "" {
+		return "", missingField("topic", "topic")
+	}
+	return result.Topic, nil
 }
 
-// CallIdentificateService: Kullanıcı ses kaydı (Speaker Enrollment)
-func CallIdentificateService(userID uint, wavData []byte) error {
+// Enroll: Kullanıcı ses kaydı (Speaker Enrollment)
+func (c *HTTPClient) Enroll(ctx context.Context, userID uint, wavData []byte) (err error) {
+	defer observe("identificate", time.Now(), &err)
+
 	payload := models.ServicePayload{
 		Speaker: fmt.Sprintf("%d", userID),
 		WavFile: wavData,
@@ -124,54 +162,21 @@ func CallIdentificateService(userID uint, wavData []byte) error {
 	}
 
 	// Audio Service üzerindeki identificate endpoint'i
-	endpoint := AudioServiceURL + "identificate"
-
-	resp, err := httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonData))
+	// Ses izini kaydettiği için idempotent değil: tekrar denenmez
+	resp, err := c.post(ctx, call{ds: c.audio, service: "identificate", path: "identificate", contentType: "application/json", body: jsonData})
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Audio servisi hata döndü (%d): %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	return decodeResponse("identificate", resp, nil)
 }
 
-// Yardımcı Fonksiyonlar (WebM -> WAV, WAV Header)
-
-func ConvertWebMToWav(webmData []byte) ([]byte, error) {
-	cmd := exec.Command("ffmpeg", "-i", "pipe:0", "-ar", "16000", "-ac", "1", "-f", "wav", "pipe:1")
-	cmd.Stdin = bytes.NewReader(webmData)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("ffmpeg dönüşüm hatası: %v", err)
+// observe: Çağrı süresini ve sonucunu (başarılı veya hata türü) metriklere işler
+func observe(service string, start time.Time, err *error) {
+	result := "success"
+	if *err != nil {
+		result = KindOf(*err)
 	}
-	return out.Bytes(), nil
-}
-
-func CreateWav(pcm []byte) []byte {
-	buf := new(bytes.Buffer)
-	writeWavHeader(buf, len(pcm))
-	buf.Write(pcm)
-	return buf.Bytes()
-}
-
-func writeWavHeader(w io.Writer, dataLength int) {
-	binary.Write(w, binary.LittleEndian, []byte("RIFF"))
-	binary.Write(w, binary.LittleEndian, int32(dataLength+36))
-	binary.Write(w, binary.LittleEndian, []byte("WAVEfmt "))
-	binary.Write(w, binary.LittleEndian, int32(16))
-	binary.Write(w, binary.LittleEndian, int16(1))
-	binary.Write(w, binary.LittleEndian, int16(1))
-	binary.Write(w, binary.LittleEndian, int32(models.SampleRate))
-	binary.Write(w, binary.LittleEndian, int32(models.SampleRate*2))
-	binary.Write(w, binary.LittleEndian, int16(2))
-	binary.Write(w, binary.LittleEndian, int16(16))
-	binary.Write(w, binary.LittleEndian, []byte("data"))
-	binary.Write(w, binary.LittleEndian, int32(dataLength))
+	metrics.ObserveService(service, start, result)
 }
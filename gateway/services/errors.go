@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Servis hatası türleri. errors.Is ile kontrol edilir:
+//
+//	if errors.Is(err, services.ErrUnavailable) { ... }
+var (
+	ErrUnavailable = errors.New("service unavailable") // Ağ hatası, 429/502/503/504, devre açık
+	ErrBadInput    = errors.New("bad input")           // 4xx: servis isteği reddetti
+	ErrModelError  = errors.New("model error")         // 500: model/işleme hatası
+	ErrDecode      = errors.New("decode error")        // Yanıt beklenen formatta değil
+)
+
+// ServiceError: Python servisinden gelen hata; servisin döndüğü mesaj korunur
+type ServiceError struct {
+	Service string // Çağrı etiketi (örn. "whisper", "text_sentiment")
+	Kind    error  // ErrUnavailable, ErrBadInput, ErrModelError veya ErrDecode
+	Status  int    // HTTP durum kodu (yanıt yoksa 0)
+	Message string // Servisin "error" alanı veya düz metin gövdesi
+	Err     error  // Alttaki hata (varsa)
+}
+
+func (e *ServiceError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v", e.Service, e.Kind)
+	if e.Status != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.Status)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+func (e *ServiceError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// KindOf: Metrik/log etiketi olarak hata türü ("" hata yoksa)
+func KindOf(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrBadInput):
+		return "bad_input"
+	case errors.Is(err, ErrModelError):
+		return "model_error"
+	case errors.Is(err, ErrDecode):
+		return "decode_error"
+	default:
+		return "unknown"
+	}
+}
+
+// statusKind: HTTP durum kodunun hata türü (nil: başarılı)
+func statusKind(code int) error {
+	switch {
+	case code < 300:
+		return nil
+	case code == http.StatusTooManyRequests, code == http.StatusBadGateway,
+		code == http.StatusServiceUnavailable, code == http.StatusGatewayTimeout:
+		return ErrUnavailable
+	case code >= 500:
+		return ErrModelError
+	default:
+		return ErrBadInput
+	}
+}
+
+// decodeResponse: Durum kodunu kontrol eder; hata ise servisin mesajını taşıyan ServiceError döner,
+// başarılıysa gövdeyi v'ye çözer (v nil ise gövde atılır).
+func decodeResponse(service string, resp *http.Response, v any) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &ServiceError{Service: service, Kind: ErrUnavailable, Status: resp.StatusCode, Err: err}
+	}
+
+	if kind := statusKind(resp.StatusCode); kind != nil {
+		return &ServiceError{Service: service, Kind: kind, Status: resp.StatusCode, Message: errorMessage(body)}
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return &ServiceError{Service: service, Kind: ErrDecode, Status: resp.StatusCode, Err: err}
+	}
+	return nil
+}
+
+// errorMessage: Flask servisleri {"error": "..."} veya düz metin döner
+func errorMessage(body []byte) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		return payload.Error
+	}
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 512 {
+		msg = msg[:512] + "..."
+	}
+	return msg
+}
+
+// missingField: 200 yanıtında zorunlu alan yoksa
+func missingField(service, field string) error {
+	return &ServiceError{Service: service, Kind: ErrDecode, Status: http.StatusOK, Message: fmt.Sprintf("yanıtta %q alanı yok", field)}
+}
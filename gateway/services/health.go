@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// servicePinger: Bir Python servisinin Pinger'ı; istemcinin yapılandırılmış adresini kullanır
+type servicePinger struct {
+	c  *HTTPClient
+	ds *downstream
+}
+
+// Pingers: Her Python servisi için servis adıyla (whisper, audio, text) bir Pinger
+func (c *HTTPClient) Pingers() map[string]Pinger {
+	out := make(map[string]Pinger, 3)
+	for _, ds := range []*downstream{c.whisper, c.audio, c.text} {
+		out[ds.name] = servicePinger{c: c, ds: ds}
+	}
+	return out
+}
+
+// Ping: Servisin HTTP üzerinden erişilebilir olup olmadığını kontrol eder.
+// Python servislerinde ayrı bir sağlık endpoint'i olmadığı için kök adrese GET atılır;
+// 4xx (örn. 404/405) servis ayakta demektir, sadece bağlantı hatası ve 5xx başarısız sayılır.
+// Devre kesiciden geçmez: açık devre de servisin gerçek durumunu göstermelidir.
+func (p servicePinger) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.ds.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("servis %d döndü", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"gateway/logging"
+	"gateway/metrics"
+	"gateway/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// call: Tek bir servis çağrısının tanımı
+type call struct {
+	ds          *downstream
+	service     string // Log/metrik etiketi (örn. "text_sentiment")
+	path        string // ds.baseURL'e eklenen endpoint
+	contentType string
+	body        []byte
+	idempotent  bool // Sadece idempotent çağrılar tekrar denenir
+}
+
+// post: Python servislerine yapılan tüm POST isteklerinin ortak yolu.
+// İsteği context'e bağlar ve sonucu oturumun logger'ı ile servis adını ekleyerek loglar.
+// Her çağrı bir client span'i açar ve trace-context başlıklarını Python servislerine taşır.
+// Devre açıksa hiç istek atmadan ErrCircuitOpen döner; idempotent çağrılarda ağ hataları,
+// 429/502/503/504 yanıtları servis politikasına göre jitter'lı üstel beklemeyle tekrar denenir.
+// Ağ hataları ErrUnavailable türünde ServiceError olarak döner; son denemenin HTTP yanıtı
+// (hata kodu dahil) decodeResponse ile yorumlanmak üzere çağırana olduğu gibi verilir.
+func (c *HTTPClient) post(ctx context.Context, cl call) (_ *http.Response, err error) {
+	url := cl.ds.baseURL + cl.path
+	ctx, span := tracing.Start(ctx, "POST "+cl.service,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(logging.KeyService, cl.service),
+			attribute.String("http.request.method", http.MethodPost),
+			attribute.String("url.full", url),
+			attribute.Int("http.request.body.size", len(cl.body)),
+		))
+	defer tracing.End(span, &err)
+
+	log := logging.From(ctx).With(logging.KeyService, cl.service)
+
+	attempts := 1
+	if cl.idempotent {
+		attempts = cl.ds.policy.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		probe, err := cl.ds.breaker.allow()
+		if err != nil {
+			log.Warn("service request rejected", "error", err)
+			return nil, &ServiceError{Service: cl.service, Kind: ErrUnavailable, Err: err}
+		}
+
+		start := time.Now()
+		resp, err := c.do(ctx, url, cl)
+		elapsed := time.Since(start).Milliseconds()
+		retryable := isRetryable(resp, err)
+		// İptal edilen istekler servisin sağlığı hakkında bilgi vermez
+		if ctx.Err() == nil {
+			cl.ds.breaker.record(!retryable)
+		} else {
+			cl.ds.breaker.release(probe)
+		}
+
+		if err != nil {
+			log.Warn("service request failed", "url", url, "attempt", attempt, "duration_ms", elapsed, "error", err)
+		} else {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			log.Debug("service request completed", "url", url, "attempt", attempt, "status", resp.StatusCode, "duration_ms", elapsed)
+		}
+
+		if !retryable || attempt >= attempts || ctx.Err() != nil {
+			span.SetAttributes(attribute.Int("http.request.resend_count", attempt-1))
+			if err != nil {
+				return nil, &ServiceError{Service: cl.service, Kind: ErrUnavailable, Err: err}
+			}
+			return resp, nil
+		}
+
+		// Tekrar denenecek: başarısız yanıtın gövdesini bırak
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		wait := cl.ds.backoff(attempt - 1)
+		metrics.ServiceRetries.WithLabelValues(cl.service).Inc()
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1), attribute.Int64("backoff_ms", wait.Milliseconds())))
+		log.Info("retrying service request", "attempt", attempt+1, "max_attempts", attempts, "backoff_ms", wait.Milliseconds())
+		if err := sleep(ctx, wait); err != nil {
+			return nil, &ServiceError{Service: cl.service, Kind: ErrUnavailable, Err: err}
+		}
+	}
+}
+
+// do: Tek bir HTTP denemesi
+func (c *HTTPClient) do(ctx context.Context, url string, cl call) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(cl.body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", cl.contentType)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return c.client.Do(req)
+}
+
+// isRetryable: Ağ hataları ve servisin geçici olarak erişilemez olduğunu belirten
+// durum kodları (429, 502, 503, 504). 500 model hatasıdır; tekrar denemek sonucu değiştirmez.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return errors.Is(statusKind(resp.StatusCode), ErrUnavailable)
+}
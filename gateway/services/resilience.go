@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"gateway/config"
+	"gateway/metrics"
+)
+
+// ErrCircuitOpen: Servis sağlıksız kabul edildiği için istek hiç gönderilmedi
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// downstream: Bir Python servisi (whisper, audio, text) ve ona ait tekrar deneme / devre kesici ayarları
+type downstream struct {
+	name    string
+	baseURL string
+	policy  config.ServicePolicy
+	breaker *breaker
+}
+
+func newDownstream(name, baseURL string, policy config.ServicePolicy) *downstream {
+	return &downstream{
+		name:    name,
+		baseURL: baseURL,
+		policy:  policy,
+		breaker: newBreaker(name, policy.BreakerThreshold, policy.BreakerCooldown.Duration),
+	}
+}
+
+// backoff: attempt. denemeden sonraki bekleme süresi (üstel artış + tam jitter)
+func (d *downstream) backoff(attempt int) time.Duration {
+	ceiling := d.policy.BaseDelay.Duration << attempt
+	if ceiling <= 0 || ceiling > d.policy.MaxDelay.Duration {
+		ceiling = d.policy.MaxDelay.Duration
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// sleep: Bekler; context iptal edilirse erken döner
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// --- Devre Kesici (Circuit Breaker) ---
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateHalfOpen:
+		return "half_open"
+	case stateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker: Ardışık threshold hatadan sonra açılır ve cooldown boyunca istekleri hemen reddeder.
+// Süre dolunca tek bir deneme isteğine izin verir (half-open); başarılıysa kapanır, değilse tekrar açılır.
+type breaker struct {
+	name      string
+	threshold int // 0: devre kesici kapalı
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	probe     uint64 // Süren deneme isteğinin kimliği (0: yok)
+	lastProbe uint64
+}
+
+func newBreaker(name string, threshold int, cooldown time.Duration) *breaker {
+	b := &breaker{name: name, threshold: threshold, cooldown: cooldown}
+	metrics.BreakerState.WithLabelValues(name).Set(float64(stateClosed))
+	return b
+}
+
+// allow: İstek gönderilebilir mi? İzin verilen istek half-open denemesiyse probe onun kimliğidir
+// (0 değil); sonucu belirsiz kalırsa release'e bu kimlik verilir.
+func (b *breaker) allow() (probe uint64, err error) {
+	if b.threshold <= 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return 0, fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+		b.transition(stateHalfOpen)
+	case stateHalfOpen:
+		if b.probe != 0 {
+			return 0, fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+	default:
+		return 0, nil
+	}
+	b.lastProbe++
+	b.probe = b.lastProbe
+	return b.probe, nil
+}
+
+// record: İsteğin sonucunu işler
+func (b *breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		b.probe = 0
+		if b.state != stateClosed {
+			b.transition(stateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.probe = 0
+		b.openedAt = time.Now()
+		if b.state != stateOpen {
+			b.transition(stateOpen)
+		}
+	}
+}
+
+// release: Sonucu belirsiz kalan (iptal edilen) isteği bırakır. Sadece süren deneme isteğinin
+// kendisiyse yeni bir denemeye yer açar; sıradan istekler veya eski denemeler sonraki denemeyi etkilemez.
+func (b *breaker) release(probe uint64) {
+	if probe == 0 {
+		return
+	}
+	b.mu.Lock()
+	if b.probe == probe {
+		b.probe = 0
+	}
+	b.mu.Unlock()
+}
+
+// transition: b.mu tutulurken çağrılır
+func (b *breaker) transition(to breakerState) {
+	from := b.state
+	b.state = to
+	metrics.BreakerState.WithLabelValues(b.name).Set(float64(to))
+
+	log := slog.Default().With("service", b.name, "from", from.String(), "to", to.String())
+	if to == stateOpen {
+		log.Warn("circuit breaker opened", "consecutive_failures", b.failures, "cooldown", b.cooldown.String())
+	} else {
+		log.Info("circuit breaker state changed")
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gateway/config"
+)
+
+func TestBackoffBoundsAndJitter(t *testing.T) {
+	ms := func(v int) config.Duration { return config.Duration{Duration: time.Duration(v) * time.Millisecond} }
+	for _, tc := range []struct {
+		name     string
+		base     config.Duration
+		max      config.Duration
+		attempt  int
+		wantCeil time.Duration // Beklemenin üst sınırı; jitter (0, wantCeil] aralığına yayar
+	}{
+		{"first retry", ms(100), ms(2000), 0, 100 * time.Millisecond},
+		{"doubles per attempt", ms(100), ms(2000), 3, 800 * time.Millisecond},
+		{"capped at max_delay", ms(100), ms(2000), 5, 2 * time.Second},
+		{"shift overflow capped", ms(100), ms(2000), 70, 2 * time.Second},
+		{"base above max", ms(5000), ms(1000), 0, time.Second},
+		{"no delay configured", ms(0), ms(0), 2, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDownstream("test", "", config.ServicePolicy{MaxAttempts: 3, BaseDelay: tc.base, MaxDelay: tc.max})
+			lo, hi := time.Duration(1<<62), time.Duration(0)
+			for range 2000 {
+				w := d.backoff(tc.attempt)
+				lo, hi = min(lo, w), max(hi, w)
+			}
+			if tc.wantCeil == 0 {
+				if hi != 0 {
+					t.Fatalf("backoff = %v, want 0", hi)
+				}
+				return
+			}
+			if lo <= 0 || hi > tc.wantCeil {
+				t.Fatalf("backoff range [%v, %v], want within (0, %v]", lo, hi, tc.wantCeil)
+			}
+			// Tam jitter: örnekler aralığın iki ucuna da yaklaşır
+			if lo > tc.wantCeil/10 || hi < tc.wantCeil*9/10 {
+				t.Errorf("backoff range [%v, %v] does not spread over (0, %v]", lo, hi, tc.wantCeil)
+			}
+		})
+	}
+}
+
+// TestBreakerTransitions: Adımlar sırayla uygulanır:
+//
+//	ok / fail      isteğin sonucu kaydedilir (record)
+//	cooldown       bekleme süresi dolmuş sayılır
+//	allow          izin verilmeli (half-open'da dönen deneme kimliği "allow:p1" ile adlandırılır)
+//	reject         ErrCircuitOpen ile reddedilmeli
+//	release:p1     p1 adlı isteğin sonucu belirsiz kaldı ("release:-": deneme olmayan istek)
+func TestBreakerTransitions(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		steps []string
+		want  breakerState
+	}{
+		{"stays closed below threshold", []string{"fail", "fail", "allow"}, stateClosed},
+		{"success resets failure count", []string{"fail", "fail", "ok", "fail", "fail", "allow"}, stateClosed},
+		{"opens at threshold", []string{"fail", "fail", "fail", "reject"}, stateOpen},
+		{"half-open after cooldown", []string{"fail", "fail", "fail", "cooldown", "allow:p1"}, stateHalfOpen},
+		{"single probe in flight", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "reject"}, stateHalfOpen},
+		{"probe success closes", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "ok", "allow", "allow"}, stateClosed},
+		{"probe failure reopens", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "fail", "reject"}, stateOpen},
+		{"reopened breaker probes again", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "fail", "cooldown", "allow:p2"}, stateHalfOpen},
+		{"released probe frees the slot", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "release:p1", "allow:p2"}, stateHalfOpen},
+		{"non-probe release keeps the slot", []string{"fail", "fail", "fail", "cooldown", "allow:p1", "release:-", "reject"}, stateHalfOpen},
+		{"stale probe release keeps the slot", []string{
+			"fail", "fail", "fail", "cooldown", "allow:p1", "fail", "cooldown", "allow:p2", "release:p1", "reject",
+		}, stateHalfOpen},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newBreaker("test", 3, time.Minute)
+			probes := map[string]uint64{}
+			for i, step := range tc.steps {
+				op, name, _ := strings.Cut(step, ":")
+				switch op {
+				case "ok", "fail":
+					b.record(op == "ok")
+				case "cooldown":
+					b.mu.Lock()
+					b.openedAt = b.openedAt.Add(-b.cooldown)
+					b.mu.Unlock()
+				case "allow":
+					probe, err := b.allow()
+					if err != nil {
+						t.Fatalf("step %d (%s): allow = %v, want allowed", i, step, err)
+					}
+					if name != "" && probe == 0 {
+						t.Fatalf("step %d (%s): allowed request is not the probe", i, step)
+					}
+					probes[name] = probe
+				case "reject":
+					if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d (%s): allow = %v, want ErrCircuitOpen", i, step, err)
+					}
+				case "release":
+					b.release(probes[name])
+				default:
+					t.Fatalf("unknown step %q", step)
+				}
+			}
+			if b.state != tc.want {
+				t.Errorf("state = %s, want %s", b.state, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker("test", 0, time.Minute)
+	for range 10 {
+		b.record(false)
+	}
+	if probe, err := b.allow(); err != nil || probe != 0 {
+		t.Errorf("allow = (%d, %v), want a plain allowed request when the breaker is disabled", probe, err)
+	}
+}
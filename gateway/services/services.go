@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+
+	"gateway/models"
+)
+
+// Handler'ların bağımlı olduğu dış servisler.
+// Varsayılan gerçekleme HTTPClient'tır (Python servisleri); testlerde sahteleri verilebilir.
+
+// Transcriber: Ham PCM'i metne çevirir (Whisper). language boşsa servisin varsayılanı kullanılır.
+type Transcriber interface {
+	Transcribe(ctx context.Context, pcmData []byte, language string) (models.ServicePayload, error)
+}
+
+// VoiceAnalyzer: WAV üzerinden ses duygusu ve konuşmacı tanıma (Audio Service)
+type VoiceAnalyzer interface {
+	AnalyzeVoice(ctx context.Context, wavData []byte) (models.ServicePayload, error)
+}
+
+// TextAnalyzer: Metin duygu analizi (Text Service)
+type TextAnalyzer interface {
+	AnalyzeSentiment(ctx context.Context, text string) (string, error)
+}
+
+// TopicAnalyzer: Konuşmanın tamamı için konu analizi (Text Service)
+type TopicAnalyzer interface {
+	AnalyzeTopic(ctx context.Context, fullText string) (string, error)
+}
+
+// Enroller: Kullanıcının ses izini kaydeder (Audio Service)
+type Enroller interface {
+	Enroll(ctx context.Context, userID uint, wavData []byte) error
+}
+
+// Pinger: Tek bir servisin erişilebilirliğini kontrol eder (/readyz)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
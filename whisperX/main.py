@@ -23,8 +23,11 @@ def transcription():
 
         print(f"Received audio chunk size: {len(audio_array)} samples")
 
+        # Gateway oturumun dilini ?language= ile iletir (varsayılan Türkçe)
+        language = request.args.get("language", "tr")
+
         # 4. Transkripsiyon
-        result = model.transcribe(audio_array, language="tr", batch_size=16)
+        result = model.transcribe(audio_array, language=language, batch_size=16)
         print(result)
 
     except Exception as e: